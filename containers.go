@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// loadPortAllocator creates the host port allocator from HOST_PORT_RANGE, e.g. 20000-20999.
+// Without the variable host ports left to 0 are picked by the daemon.
+func loadPortAllocator() (*docker.PortAllocator, error) {
+	portRange := os.Getenv("HOST_PORT_RANGE")
+	if portRange == "" {
+		return nil, nil
+	}
+
+	bounds := strings.SplitN(portRange, "-", 2)
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("Invalid HOST_PORT_RANGE %s", portRange)
+	}
+	first, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid HOST_PORT_RANGE %s", portRange)
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid HOST_PORT_RANGE %s", portRange)
+	}
+
+	return docker.NewPortAllocator(first, last)
+}
+
+func (c *Controller) getContainerPorts(w http.ResponseWriter, r *http.Request) {
+	log.Println("Getting container ports")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	ports, err := c.docker.ContainerPorts(r.Context(), mux.Vars(r)["id"])
+	if err == docker.ErrContainerNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ports)
+}
+
+// containerErrorStatus returns the response status of an error returned by a container operation.
+func containerErrorStatus(err error) int {
+	if _, ok := err.(*docker.ValidationError); ok {
+		return http.StatusBadRequest
+	}
+	switch err {
+	case docker.ErrContainerNotFound:
+		return http.StatusNotFound
+	case docker.ErrContainerNotRunning, docker.ErrContainerNotPaused:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// queryBool parses the boolean url param name, returning fallback if it is not set.
+func queryBool(r *http.Request, name string, fallback bool) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid url param '%s'", name)
+	}
+	return parsed, nil
+}
+
+// logsOptions reads the log options from the url params. stdout and stderr
+// default to true, tail to all lines.
+func logsOptions(r *http.Request) (docker.LogOptions, error) {
+	query := r.URL.Query()
+	options := docker.LogOptions{
+		Since: query.Get("since"),
+		Until: query.Get("until"),
+		Tail:  query.Get("tail"),
+	}
+	if options.Tail == "" {
+		options.Tail = "all"
+	} else if _, err := strconv.Atoi(options.Tail); err != nil && options.Tail != "all" {
+		return options, errors.New("Invalid url param 'tail'")
+	}
+
+	var err error
+	if options.Stdout, err = queryBool(r, "stdout", true); err != nil {
+		return options, err
+	}
+	if options.Stderr, err = queryBool(r, "stderr", true); err != nil {
+		return options, err
+	}
+	if options.Timestamps, err = queryBool(r, "timestamps", false); err != nil {
+		return options, err
+	}
+	if options.Follow, err = queryBool(r, "follow", false); err != nil {
+		return options, err
+	}
+	if !options.Stdout && !options.Stderr {
+		return options, errors.New("At least one of 'stdout' and 'stderr' has to be selected")
+	}
+	return options, nil
+}
+
+type logsResult struct {
+	Status string `json:"status"`
+	Lines  int    `json:"lines"`
+}
+
+// getContainerLogs sends the container output line by line as newline delimited
+// JSON, Server-Sent Events (stream=sse) or WebSocket messages, if the
+// connection is upgraded. With follow=true new output is sent as it is produced.
+func (c *Controller) getContainerLogs(w http.ResponseWriter, r *http.Request) {
+	log.Println("Getting container logs")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	options, err := logsOptions(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	format := r.URL.Query().Get("stream")
+	if format == "" {
+		format = streamNDJSON
+	}
+	if format != streamNDJSON && format != streamSSE {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid stream format %s", format)
+		return
+	}
+
+	ctx := r.Context()
+	if !options.Follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	logs, err := c.docker.ContainerLogs(ctx, mux.Vars(r)["id"], options)
+	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	defer logs.Close()
+
+	var stream eventSender
+	if websocket.IsWebSocketUpgrade(r) {
+		socket, err := newWebsocketStream(w, r)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		// Closing the socket stops following the logs.
+		go func() {
+			<-socket.readMessages(ctx, nil).Done()
+			logs.Close()
+		}()
+		stream = socket
+	} else {
+		stream, err = newStreamWriter(w, format)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+	}
+
+	lines := 0
+	err = logs.ReadLines(func(line docker.LogLine) error {
+		lines++
+		return stream.Send("log", line)
+	})
+	stream.Finish(logsResult{Status: "complete", Lines: lines}, err)
+}
+
+// getContainerStats sends the resource usage of the container, either a
+// single sample as json (snapshot=true) or a sample every interval (default 1s)
+// as newline delimited JSON, Server-Sent Events (stream=sse) or WebSocket messages.
+func (c *Controller) getContainerStats(w http.ResponseWriter, r *http.Request) {
+	log.Println("Getting container stats")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	snapshot, err := queryBool(r, "snapshot", false)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	interval := time.Second
+	if value := r.URL.Query().Get("interval"); value != "" {
+		interval, err = time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Invalid url param 'interval'")
+			return
+		}
+	}
+	format := r.URL.Query().Get("stream")
+	if format == "" {
+		format = streamNDJSON
+	}
+	if format != streamNDJSON && format != streamSSE {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid stream format %s", format)
+		return
+	}
+
+	ID := mux.Vars(r)["id"]
+	if snapshot {
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+		defer cancel()
+
+		stats, err := c.docker.StatsSnapshot(ctx, ID)
+		if err != nil {
+			w.WriteHeader(containerErrorStatus(err))
+			fmt.Fprint(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, stats)
+		return
+	}
+
+	reader, err := c.docker.StreamStats(r.Context(), ID)
+	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	defer reader.Close()
+
+	var stream eventSender
+	if websocket.IsWebSocketUpgrade(r) {
+		socket, err := newWebsocketStream(w, r)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		// Closing the socket stops the stats.
+		go func() {
+			<-socket.readMessages(r.Context(), nil).Done()
+			reader.Close()
+		}()
+		stream = socket
+	} else {
+		stream, err = newStreamWriter(w, format)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+	}
+
+	err = reader.ReadStats(interval, func(stats docker.ContainerStats) error {
+		return stream.Send("stats", stats)
+	})
+	stream.Finish(streamCompleted{Status: "complete"}, err)
+}
+
+// inspectContainer returns the state, configuration and network settings of the container.
+func (c *Controller) inspectContainer(w http.ResponseWriter, r *http.Request) {
+	log.Println("Inspecting container")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	details, err := c.docker.InspectContainer(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, details)
+}
+
+// changeContainerState applies action to the container of the route and
+// replies with the resulting state of the container.
+func (c *Controller) changeContainerState(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, ID string) error) {
+	ID := mux.Vars(r)["id"]
+	if err := action(r.Context(), ID); err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	details, err := c.docker.InspectContainer(r.Context(), ID)
+	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, details.State)
+}
+
+func (c *Controller) pauseContainer(w http.ResponseWriter, r *http.Request) {
+	log.Println("Pausing container")
+	if err := checkRequestType(POST, w, r); err != nil {
+		return
+	}
+
+	c.changeContainerState(w, r, c.docker.PauseContainer)
+}
+
+func (c *Controller) unpauseContainer(w http.ResponseWriter, r *http.Request) {
+	log.Println("Unpausing container")
+	if err := checkRequestType(POST, w, r); err != nil {
+		return
+	}
+
+	c.changeContainerState(w, r, c.docker.UnpauseContainer)
+}
+
+// restartContainer restarts the container. The url param timeout is the number
+// of seconds to wait for the container to stop before killing it.
+func (c *Controller) restartContainer(w http.ResponseWriter, r *http.Request) {
+	log.Println("Restarting container")
+	if err := checkRequestType(POST, w, r); err != nil {
+		return
+	}
+
+	timeout := time.Duration(-1)
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Invalid url param 'timeout'")
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	// The stop timeout extends the request deadline.
+	deadline := requestTimeout
+	if timeout > 0 {
+		deadline += timeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), deadline)
+	defer cancel()
+
+	c.changeContainerState(w, r.WithContext(ctx), func(ctx context.Context, ID string) error {
+		return c.docker.RestartContainer(ctx, ID, timeout)
+	})
+}
+
+// killContainer sends the signal given in the url param signal, SIGKILL by default.
+func (c *Controller) killContainer(w http.ResponseWriter, r *http.Request) {
+	log.Println("Killing container")
+	if err := checkRequestType(POST, w, r); err != nil {
+		return
+	}
+
+	signal := r.URL.Query().Get("signal")
+	c.changeContainerState(w, r, func(ctx context.Context, ID string) error {
+		return c.docker.KillContainer(ctx, ID, signal)
+	})
+}
+
+// defaultWaitTimeout limits how long /containers/{id}/wait blocks without a timeout url param.
+const defaultWaitTimeout = 5 * time.Minute
+
+// waitContainer blocks until the container reaches the condition given in the
+// url param condition (not-running, next-exit or removed) and replies with its
+// exit code. The url param timeout, e.g. 30s, limits the wait, running out of
+// it results in 504 Gateway Timeout.
+func (c *Controller) waitContainer(w http.ResponseWriter, r *http.Request) {
+	log.Println("Waiting for container")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	timeout := defaultWaitTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		var err error
+		timeout, err = time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Invalid url param 'timeout'")
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	condition := docker.WaitCondition(r.URL.Query().Get("condition"))
+	result, err := c.docker.WaitContainer(ctx, mux.Vars(r)["id"], condition)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		fmt.Fprint(w, "Timed out waiting for the container")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// pathStatHeader carries the json stat of the path of an archive response.
+const pathStatHeader = "X-Container-Path-Stat"
+
+func copyErrorStatus(err error) int {
+	if err == docker.ErrPathNotFound {
+		return http.StatusNotFound
+	}
+	return containerErrorStatus(err)
+}
+
+func setPathStatHeader(w http.ResponseWriter, stat *docker.PathStat) {
+	payload, err := json.Marshal(stat)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	w.Header().Set(pathStatHeader, string(payload))
+}
+
+// containerArchive copies files from and to the container path given in the
+// url param path:
+//
+//	GET  sends a tar archive of the path, its stat is in the X-Container-Path-Stat header
+//	HEAD only sends the X-Container-Path-Stat header
+//	PUT  extracts an application/x-tar body into the directory path, any other
+//	     body, or the "file" field of a multipart form, is written to the file path,
+//	     files larger than maxUploadSize are rejected with 413
+func (c *Controller) containerArchive(w http.ResponseWriter, r *http.Request) {
+	containerPath := r.URL.Query().Get("path")
+	if containerPath == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, errors.New("Url Param 'path' is missing"))
+		return
+	}
+
+	switch r.Method {
+	case GET:
+		c.copyFromContainer(w, r, containerPath)
+	case http.MethodHead:
+		c.statContainerPath(w, r, containerPath)
+	case http.MethodPut:
+		c.copyToContainer(w, r, containerPath)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid request type %s", r.Method)
+	}
+}
+
+func (c *Controller) copyFromContainer(w http.ResponseWriter, r *http.Request, containerPath string) {
+	log.Println("Copying from container")
+	archive, stat, err := c.docker.CopyFromContainer(r.Context(), mux.Vars(r)["id"], containerPath)
+	if err != nil {
+		w.WriteHeader(copyErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	defer archive.Close()
+
+	setPathStatHeader(w, stat)
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.tar"`, stat.Name))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, archive); err != nil {
+		log.Println(errors.Wrap(errors.WithStack(err), "Failed to send container archive"))
+	}
+}
+
+func (c *Controller) statContainerPath(w http.ResponseWriter, r *http.Request, containerPath string) {
+	log.Println("Getting container path stat")
+	stat, err := c.docker.StatContainerPath(r.Context(), mux.Vars(r)["id"], containerPath)
+	if err != nil {
+		w.WriteHeader(copyErrorStatus(err))
+		return
+	}
+
+	setPathStatHeader(w, stat)
+	w.WriteHeader(http.StatusOK)
+}
+
+// maxUploadSize limits the size of a file uploaded without a tar archive, as
+// it may be spooled to disk before it is copied.
+const maxUploadSize = 1 << 30
+
+// uploadErrorStatus returns 413 if the upload exceeded maxUploadSize, status otherwise.
+func uploadErrorStatus(err error, status int) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return status
+}
+
+// spoolUpload copies an upload of unknown size to a temporary file, so its size is known.
+// The file is removed when it is closed.
+func spoolUpload(upload io.Reader) (*os.File, int64, error) {
+	file, err := os.CreateTemp("", "upload")
+	if err != nil {
+		return nil, 0, err
+	}
+	// The open file stays readable, the name is not needed anymore.
+	if err := os.Remove(file.Name()); err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	size, err := io.Copy(file, upload)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, size, nil
+}
+
+func (c *Controller) copyToContainer(w http.ResponseWriter, r *http.Request, containerPath string) {
+	log.Println("Copying to container")
+	ID := mux.Vars(r)["id"]
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var err error
+	if mediaType == "application/x-tar" {
+		err = c.docker.CopyToContainer(r.Context(), ID, containerPath, r.Body)
+	} else {
+		mode := os.FileMode(0644)
+		if value := r.URL.Query().Get("mode"); value != "" {
+			parsed, errParse := strconv.ParseUint(value, 8, 32)
+			if errParse != nil || parsed > 0777 {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, "Invalid url param 'mode'")
+				return
+			}
+			mode = os.FileMode(parsed)
+		}
+
+		if r.ContentLength > maxUploadSize {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			fmt.Fprintf(w, "Upload exceeds %d bytes", maxUploadSize)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+		upload, errUpload := uploadedFile(r)
+		if errUpload != nil {
+			w.WriteHeader(uploadErrorStatus(errUpload, http.StatusBadRequest))
+			fmt.Fprint(w, errUpload.Error())
+			return
+		}
+		size := r.ContentLength
+		if mediaType == "multipart/form-data" || size < 0 {
+			file, spooled, errSpool := spoolUpload(upload)
+			if errSpool != nil {
+				w.WriteHeader(uploadErrorStatus(errSpool, http.StatusInternalServerError))
+				fmt.Fprint(w, errSpool.Error())
+				return
+			}
+			defer file.Close()
+			upload, size = file, spooled
+		}
+
+		err = c.docker.CopyFileToContainer(r.Context(), ID, containerPath, upload, size, mode)
+	}
+	if err != nil {
+		w.WriteHeader(copyErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	stat, err := c.docker.StatContainerPath(r.Context(), ID, containerPath)
+	if err != nil {
+		w.WriteHeader(copyErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, stat)
+}
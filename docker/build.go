@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+const defaultDockerfile = "Dockerfile"
+
+// BuildOptions holds the optional settings of an image build.
+type BuildOptions struct {
+	// Dockerfile is the path of the Dockerfile relative to the build context.
+	Dockerfile string `json:"dockerfile,omitempty"`
+	// Tags are applied in addition to the image name.
+	Tags      []string          `json:"tags,omitempty"`
+	BuildArgs map[string]string `json:"build-args,omitempty"`
+	// Target is the stage of a multi-stage Dockerfile to build.
+	Target string            `json:"target,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Pull always attempts to pull a newer version of the base images.
+	Pull    bool `json:"pull,omitempty"`
+	NoCache bool `json:"no-cache,omitempty"`
+}
+
+func (o *BuildOptions) dockerfile() (string, error) {
+	if o.Dockerfile == "" {
+		return defaultDockerfile, nil
+	}
+
+	dockerfile := filepath.Clean(o.Dockerfile)
+	if filepath.IsAbs(dockerfile) || dockerfile == ".." || strings.HasPrefix(dockerfile, ".."+string(filepath.Separator)) {
+		return "", &ValidationError{Field: "dockerfile", Message: "must be inside the build context"}
+	}
+	return filepath.ToSlash(dockerfile), nil
+}
+
+func (o *BuildOptions) imageBuildOptions(imageName string, dockerfile string) types.ImageBuildOptions {
+	buildArgs := make(map[string]*string, len(o.BuildArgs))
+	for key := range o.BuildArgs {
+		value := o.BuildArgs[key]
+		buildArgs[key] = &value
+	}
+
+	return types.ImageBuildOptions{
+		Dockerfile: dockerfile,
+		Tags:       append([]string{imageName}, o.Tags...),
+		BuildArgs:  buildArgs,
+		Target:     o.Target,
+		Labels:     managedLabels(o.Labels),
+		PullParent: o.Pull,
+		NoCache:    o.NoCache,
+		Remove:     true,
+	}
+}
+
+// CreateImage builds the image imageName from the source in filePath and returns its ID.
+// The build output is passed to progress, if it is not nil.
+func (m *Manager) CreateImage(ctx context.Context, filePath string, imageName string, options BuildOptions, progress ProgressFunc) (string, error) {
+	ctx, cancel := withTimeout(ctx, m.BuildTimeout)
+	defer cancel()
+
+	dockerfile, err := options.dockerfile()
+	if err != nil {
+		return "", err
+	}
+	buildOptions := options.imageBuildOptions(imageName, dockerfile)
+
+	dockerBuildContext, err := buildContext(filePath, dockerfile)
+	if err != nil {
+		return "", err
+	}
+	defer dockerBuildContext.Close()
+
+	buildOptions.Context = dockerBuildContext
+	imageBuildResponse, err := m.client.ImageBuild(ctx, dockerBuildContext, buildOptions)
+	if err != nil {
+		return "", err
+	}
+	defer imageBuildResponse.Body.Close()
+
+	imageID := ""
+	err = readJSONMessages(imageBuildResponse.Body, func(message JSONMessage) error {
+		if ID := message.auxID(); ID != "" {
+			imageID = ID
+		}
+		if progress != nil {
+			return progress(message)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if imageID != "" {
+		return imageID, nil
+	}
+
+	// Older daemons do not report the ID of the built image.
+	images, err := m.client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return "", err
+	}
+	return GetImageIDByTag(images, imageName)
+}
@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"github.com/pkg/errors"
+)
+
+const dockerignoreFile = ".dockerignore"
+
+// readIgnorePatterns reads the .dockerignore file of sourceDirectory.
+// A missing file results in no patterns.
+func readIgnorePatterns(sourceDirectory string) ([]string, error) {
+	file, err := os.Open(filepath.Join(sourceDirectory, dockerignoreFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	patterns, err := ignorefile.ReadAll(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid "+dockerignoreFile)
+	}
+	return patterns, nil
+}
+
+// keepBuildFiles re-includes the Dockerfile and the .dockerignore file, if the
+// patterns exclude them, as the daemon needs them.
+func keepBuildFiles(patterns []string, dockerfile string) ([]string, error) {
+	for _, file := range []string{dockerignoreFile, filepath.ToSlash(filepath.Clean(dockerfile))} {
+		excluded, err := patternmatcher.MatchesOrParentMatches(file, patterns)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid "+dockerignoreFile+" pattern")
+		}
+		if excluded {
+			patterns = append(patterns, "!"+file)
+		}
+	}
+	return patterns, nil
+}
+
+// buildContext streams sourceDirectory as a tar archive, leaving out the paths
+// listed in its .dockerignore. The Dockerfile and the .dockerignore file itself
+// are always sent, as the daemon needs them. The archive is produced on the fly,
+// nothing is written to disk. Closing the returned reader stops the producer.
+func buildContext(sourceDirectory string, dockerfile string) (io.ReadCloser, error) {
+	info, err := os.Stat(sourceDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New("Build context " + sourceDirectory + " is not a directory")
+	}
+
+	patterns, err := readIgnorePatterns(sourceDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if patterns, err = keepBuildFiles(patterns, dockerfile); err != nil {
+		return nil, err
+	}
+
+	return archive.TarWithOptions(sourceDirectory, &archive.TarOptions{
+		ExcludePatterns: patterns,
+		Compression:     archive.Uncompressed,
+		// Ownership of the build host is meaningless inside the image.
+		ChownOpts: &idtools.Identity{UID: 0, GID: 0},
+	})
+}
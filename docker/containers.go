@@ -0,0 +1,229 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// ValidationError reports an invalid field of a container, network or volume spec.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid '%s': %s", e.Field, e.Message)
+}
+
+// MountSpec describes a bind mount, named volume or tmpfs of a container.
+type MountSpec struct {
+	// Type is one of bind, volume or tmpfs.
+	Type     string `json:"type"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read-only"`
+}
+
+// RestartPolicySpec describes when the daemon restarts a container.
+type RestartPolicySpec struct {
+	// Name is one of no, always, on-failure or unless-stopped.
+	Name string `json:"name"`
+	// MaximumRetryCount is only valid with on-failure.
+	MaximumRetryCount int `json:"maximum-retry-count"`
+}
+
+// ContainerSpec describes a container to create.
+type ContainerSpec struct {
+	Name       string            `json:"name"`
+	Image      string            `json:"image-name"`
+	Env        []string          `json:"env"`
+	Cmd        []string          `json:"cmd"`
+	Entrypoint []string          `json:"entrypoint"`
+	WorkingDir string            `json:"working-dir"`
+	User       string            `json:"user"`
+	Labels     map[string]string `json:"labels"`
+	Mounts     []MountSpec       `json:"mounts"`
+	// RestartPolicy defaults to "no".
+	RestartPolicy RestartPolicySpec `json:"restart-policy"`
+	// Ports publishes container ports on the host.
+	Ports []PortMapping `json:"ports"`
+	// Address and Port publish Port of the container on the same host port.
+	// Deprecated: use Ports.
+	Address string `json:"address"`
+	Port    string `json:"port"`
+}
+
+var containerNameRegexp = regexp.MustCompile(`^/?[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+var envNameRegexp = regexp.MustCompile(`^[^=\s]+$`)
+
+// Validate checks the spec and returns a *ValidationError naming the first invalid field.
+func (s *ContainerSpec) Validate() error {
+	if s.Image == "" {
+		return &ValidationError{Field: "image-name", Message: "missing"}
+	}
+
+	if s.Name != "" && !containerNameRegexp.MatchString(s.Name) {
+		return &ValidationError{Field: "name", Message: "only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed"}
+	}
+
+	for i, variable := range s.Env {
+		if !envNameRegexp.MatchString(strings.SplitN(variable, "=", 2)[0]) {
+			return &ValidationError{Field: fmt.Sprintf("env[%d]", i), Message: "expected KEY=value"}
+		}
+	}
+
+	if s.WorkingDir != "" && !path.IsAbs(s.WorkingDir) {
+		return &ValidationError{Field: "working-dir", Message: "must be an absolute path"}
+	}
+
+	for i, m := range s.Mounts {
+		field := fmt.Sprintf("mounts[%d]", i)
+		switch mount.Type(m.Type) {
+		case mount.TypeBind:
+			if !path.IsAbs(m.Source) {
+				return &ValidationError{Field: field + ".source", Message: "bind mounts need an absolute host path"}
+			}
+		case mount.TypeVolume:
+			if m.Source == "" {
+				return &ValidationError{Field: field + ".source", Message: "missing volume name"}
+			}
+		case mount.TypeTmpfs:
+			if m.Source != "" {
+				return &ValidationError{Field: field + ".source", Message: "not supported for tmpfs"}
+			}
+		default:
+			return &ValidationError{Field: field + ".type", Message: "expected bind, volume or tmpfs"}
+		}
+		if !path.IsAbs(m.Target) {
+			return &ValidationError{Field: field + ".target", Message: "must be an absolute path"}
+		}
+	}
+
+	switch s.RestartPolicy.Name {
+	case "", "no", "always", "unless-stopped":
+		if s.RestartPolicy.MaximumRetryCount != 0 {
+			return &ValidationError{Field: "restart-policy.maximum-retry-count", Message: "only valid with on-failure"}
+		}
+	case "on-failure":
+		if s.RestartPolicy.MaximumRetryCount < 0 {
+			return &ValidationError{Field: "restart-policy.maximum-retry-count", Message: "must not be negative"}
+		}
+	default:
+		return &ValidationError{Field: "restart-policy.name", Message: "expected no, always, on-failure or unless-stopped"}
+	}
+
+	if s.Port != "" {
+		if _, err := strconv.Atoi(s.Port); err != nil {
+			return &ValidationError{Field: "port", Message: "not a port number"}
+		}
+	}
+
+	return validatePorts(s.portMappings())
+}
+
+// portMappings merges the deprecated single port into Ports.
+func (s *ContainerSpec) portMappings() []PortMapping {
+	ports := append([]PortMapping{}, s.Ports...)
+	if s.Port != "" {
+		port, _ := strconv.Atoi(s.Port)
+		ports = append(ports, PortMapping{ContainerPort: port, HostPort: port, HostIP: s.Address})
+	}
+	return ports
+}
+
+func (s *ContainerSpec) config() *container.Config {
+	exposedPorts, _ := portBindings(s.portMappings())
+	return &container.Config{
+		Image:        s.Image,
+		Env:          s.Env,
+		Cmd:          s.Cmd,
+		Entrypoint:   s.Entrypoint,
+		WorkingDir:   s.WorkingDir,
+		User:         s.User,
+		Labels:       managedLabels(s.Labels),
+		ExposedPorts: exposedPorts,
+	}
+}
+
+func (s *ContainerSpec) hostConfig() *container.HostConfig {
+	hostConfig := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name:              s.RestartPolicy.Name,
+			MaximumRetryCount: s.RestartPolicy.MaximumRetryCount,
+		},
+	}
+
+	for _, m := range s.Mounts {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+
+	_, hostConfig.PortBindings = portBindings(s.portMappings())
+
+	return hostConfig
+}
+
+// CreatedContainer describes a new container and its published ports.
+type CreatedContainer struct {
+	ID string `json:"id"`
+	// Ports holds the port mappings with the allocated host ports filled in.
+	// Host port 0 means the port is picked by the daemon when the container starts.
+	Ports []PortMapping `json:"ports"`
+}
+
+// CreateContainer validates spec and creates the container it describes.
+// Invalid specs, host ports already in use, sctp ports the daemon API cannot
+// publish and volume mounts of volumes that do not exist result in a
+// *ValidationError.
+// Host ports left to 0 are taken from the port allocator of the manager, if set.
+func (m *Manager) CreateContainer(ctx context.Context, spec ContainerSpec) (*CreatedContainer, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.checkVolumeMounts(ctx, spec.Mounts); err != nil {
+		return nil, err
+	}
+
+	// The deprecated port is checked against the ports in use like the others.
+	spec.Ports = spec.portMappings()
+	spec.Port = ""
+	spec.Address = ""
+	if err := m.checkPortProtocols(spec.Ports); err != nil {
+		return nil, err
+	}
+	allocated, err := m.assignHostPorts(ctx, spec.Ports)
+	if err != nil {
+		return nil, err
+	}
+
+	cont, err := m.client.ContainerCreate(ctx, spec.config(), spec.hostConfig(), nil, nil, spec.Name)
+	if err != nil {
+		if m.PortAllocator != nil {
+			m.PortAllocator.release(allocated)
+		}
+		err = fmt.Errorf("Failed to create docker container: %s", err.Error())
+		return nil, err
+	}
+	if m.PortAllocator != nil {
+		m.PortAllocator.assign(allocated, cont.ID)
+	}
+
+	return &CreatedContainer{
+		ID:    cont.ID,
+		Ports: spec.portMappings(),
+	}, nil
+}
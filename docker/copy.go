@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+var ErrPathNotFound = errors.New("Path not found in container")
+
+// PathStat describes a file or directory inside a container.
+type PathStat struct {
+	Name       string      `json:"name"`
+	Size       int64       `json:"size"`
+	Mode       os.FileMode `json:"mode"`
+	IsDir      bool        `json:"is-dir"`
+	ModTime    time.Time   `json:"mod-time"`
+	LinkTarget string      `json:"link-target"`
+}
+
+func pathStat(stat types.ContainerPathStat) *PathStat {
+	return &PathStat{
+		Name:       stat.Name,
+		Size:       stat.Size,
+		Mode:       stat.Mode,
+		IsDir:      stat.Mode.IsDir(),
+		ModTime:    stat.Mtime,
+		LinkTarget: stat.LinkTarget,
+	}
+}
+
+// isPathNotFound recognizes the 404 of the archive API. The container is
+// inspected beforehand, so the status refers to the path.
+func isPathNotFound(err error) bool {
+	return errdefs.IsNotFound(err)
+}
+
+func validateContainerPath(field string, containerPath string) error {
+	if !path.IsAbs(containerPath) {
+		return &ValidationError{Field: field, Message: "must be an absolute path"}
+	}
+	return nil
+}
+
+// statPath returns the stat of containerPath, ErrContainerNotFound or ErrPathNotFound.
+func (m *Manager) statPath(ctx context.Context, ID string, containerPath string) (*PathStat, error) {
+	if _, err := m.inspectContainer(ctx, ID); err != nil {
+		return nil, err
+	}
+
+	stat, err := m.client.ContainerStatPath(ctx, ID, containerPath)
+	if err != nil {
+		if isPathNotFound(err) {
+			return nil, ErrPathNotFound
+		}
+		return nil, err
+	}
+	return pathStat(stat), nil
+}
+
+// StatContainerPath describes the file or directory containerPath of the container.
+func (m *Manager) StatContainerPath(ctx context.Context, ID string, containerPath string) (*PathStat, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if err := validateContainerPath("path", containerPath); err != nil {
+		return nil, err
+	}
+	return m.statPath(ctx, ID, containerPath)
+}
+
+// CopyToContainer extracts the tar archive into the directory destination of
+// the container. Existing directories are not replaced by files and vice versa.
+func (m *Manager) CopyToContainer(ctx context.Context, ID string, destination string, archive io.Reader) error {
+	ctx, cancel := withTimeout(ctx, m.TransferTimeout)
+	defer cancel()
+
+	if err := validateContainerPath("path", destination); err != nil {
+		return err
+	}
+	stat, err := m.statPath(ctx, ID, destination)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir {
+		return &ValidationError{Field: "path", Message: "archives can only be extracted into a directory"}
+	}
+
+	return m.client.CopyToContainer(ctx, ID, destination, archive, types.CopyToContainerOptions{})
+}
+
+// CopyFileToContainer writes size bytes of content to the file destination of
+// the container, replacing it if it exists. The parent directory has to exist.
+func (m *Manager) CopyFileToContainer(ctx context.Context, ID string, destination string, content io.Reader, size int64, mode os.FileMode) error {
+	ctx, cancel := withTimeout(ctx, m.TransferTimeout)
+	defer cancel()
+
+	if err := validateContainerPath("path", destination); err != nil {
+		return err
+	}
+	if strings.HasSuffix(destination, "/") {
+		return &ValidationError{Field: "path", Message: "must be a file path"}
+	}
+	directory, name := path.Split(path.Clean(destination))
+	stat, err := m.statPath(ctx, ID, directory)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir {
+		return &ValidationError{Field: "path", Message: "parent is not a directory"}
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		tarWriter := tar.NewWriter(writer)
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     int64(mode.Perm()),
+			Size:     size,
+			ModTime:  time.Now(),
+			Typeflag: tar.TypeReg,
+		})
+		if err == nil {
+			_, err = io.CopyN(tarWriter, content, size)
+		}
+		if err == nil {
+			err = tarWriter.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+	defer reader.Close()
+
+	return m.client.CopyToContainer(ctx, ID, directory, reader, types.CopyToContainerOptions{})
+}
+
+// CopyFromContainer returns a tar archive of the file or directory source of
+// the container, along with its stat. It is up to the caller to close the reader.
+func (m *Manager) CopyFromContainer(ctx context.Context, ID string, source string) (io.ReadCloser, *PathStat, error) {
+	if err := validateContainerPath("path", source); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, m.TransferTimeout)
+	if _, err := m.statPath(ctx, ID, source); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	reader, stat, err := m.client.CopyFromContainer(ctx, ID, source)
+	if err != nil {
+		cancel()
+		if isPathNotFound(err) {
+			return nil, nil, ErrPathNotFound
+		}
+		return nil, nil, err
+	}
+	return &cancelReadCloser{ReadCloser: reader, cancel: cancel}, pathStat(stat), nil
+}
@@ -2,65 +2,145 @@ package docker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"log"
+	"regexp"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/nat"
-	"github.com/jhoonb/archivex"
+	"github.com/docker/docker/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
 
 var ErrNoImagesDeleted = errors.New("No images were deleted")
 var ErrImageNotFound = errors.New("Image not found")
 var ErrContainerNotFound = errors.New("Container not found")
+var ErrContainerNotRunning = errors.New("Container is not running")
+var ErrContainerNotPaused = errors.New("Container is not paused")
+
+// Client is the subset of the Docker API used by the package.
+// *client.Client satisfies it, tests can substitute a fake.
+type Client interface {
+	ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error)
+	ImageHistory(ctx context.Context, image string) ([]image.HistoryResponseItem, error)
+	ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error)
+	ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error)
+	ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
+	ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error)
+	ImagePush(ctx context.Context, ref string, options types.ImagePushOptions) (io.ReadCloser, error)
+	ImageRemove(ctx context.Context, image string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error)
+	ImageSave(ctx context.Context, images []string) (io.ReadCloser, error)
+	ImageTag(ctx context.Context, image, ref string) error
+
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, config types.ExecStartCheck) (types.HijackedResponse, error)
+	ContainerExecCreate(ctx context.Context, container string, config types.ExecConfig) (types.IDResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error)
+	ContainerExecResize(ctx context.Context, execID string, options types.ResizeOptions) error
+	ContainerInspect(ctx context.Context, container string) (types.ContainerJSON, error)
+	ContainerKill(ctx context.Context, container, signal string) error
+	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
+	ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error)
+	ContainerPause(ctx context.Context, container string) error
+	ContainerRemove(ctx context.Context, container string, options types.ContainerRemoveOptions) error
+	ContainerRestart(ctx context.Context, container string, options container.StopOptions) error
+	ContainerStart(ctx context.Context, container string, options types.ContainerStartOptions) error
+	ContainerStatPath(ctx context.Context, container, path string) (types.ContainerPathStat, error)
+	ContainerStats(ctx context.Context, container string, stream bool) (types.ContainerStats, error)
+	ContainerStop(ctx context.Context, container string, options container.StopOptions) error
+	ContainerUnpause(ctx context.Context, container string) error
+	ContainerWait(ctx context.Context, container string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	CopyFromContainer(ctx context.Context, container, srcPath string) (io.ReadCloser, types.ContainerPathStat, error)
+	CopyToContainer(ctx context.Context, container, path string, content io.Reader, options types.CopyToContainerOptions) error
+
+	Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+
+	NetworkConnect(ctx context.Context, networkID, container string, config *network.EndpointSettings) error
+	NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error)
+	NetworkDisconnect(ctx context.Context, networkID, container string, force bool) error
+	NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error)
+	NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error)
+	NetworkRemove(ctx context.Context, networkID string) error
+
+	VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error)
+	VolumeInspect(ctx context.Context, volumeID string) (volume.Volume, error)
+	VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+}
 
-func parseResponse(reader io.Reader) (map[string]interface{}, error) {
-	d := json.NewDecoder(reader)
-	result := make(map[string]interface{})
-	for {
-		if err := d.Decode(&result); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, err
-		}
-	}
-	return result, nil
+var _ Client = (*client.Client)(nil)
+
+// Default deadlines applied on top of the caller's context.
+const (
+	DefaultTimeout         = 30 * time.Second
+	DefaultBuildTimeout    = 10 * time.Minute
+	DefaultTransferTimeout = 10 * time.Minute
+)
+
+// Manager executes docker operations through a single shared client.
+// Every operation is bound to the caller's context and additionally limited
+// by Timeout (BuildTimeout for image builds, TransferTimeout for registry
+// transfers), so abandoned requests stop the underlying docker work.
+// A zero timeout disables the extra deadline.
+type Manager struct {
+	client          Client
+	Timeout         time.Duration
+	BuildTimeout    time.Duration
+	TransferTimeout time.Duration
+	// PortAllocator, if set, assigns the host ports left to 0 in container specs.
+	PortAllocator *PortAllocator
+	// apiVersion is the docker API version used with the daemon, empty if unknown.
+	apiVersion string
 }
 
-func tarballFolder(contextName string, sourceDirectory string) error {
-	tar := new(archivex.TarFile)
-	if err := tar.Create(contextName); err != nil {
-		if errClose := tar.Close(); errClose != nil {
-			return errors.Wrap(errors.WithStack(err), errClose.Error())
-		}
-		return err
-	}
-	if err := tar.AddAll(sourceDirectory, false); err != nil {
-		if errClose := tar.Close(); errClose != nil {
-			return errors.Wrap(errors.WithStack(err), errClose.Error())
-		}
-		return err
+// NewManager returns a Manager that uses the provided client.
+func NewManager(client Client) *Manager {
+	return &Manager{
+		client:          client,
+		Timeout:         DefaultTimeout,
+		BuildTimeout:    DefaultBuildTimeout,
+		TransferTimeout: DefaultTransferTimeout,
 	}
-	if err := tar.Close(); err != nil {
-		return err
+}
+
+// NewEnvManager returns a Manager with a client configured from the environment
+// (DOCKER_HOST, DOCKER_API_VERSION, DOCKER_CERT_PATH, DOCKER_TLS_VERIFY).
+// Without DOCKER_API_VERSION the API version is negotiated with the daemon once.
+func NewEnvManager() (*Manager, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create docker client: %s", err.Error())
 	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	cli.NegotiateAPIVersion(ctx)
+
+	m := NewManager(cli)
+	m.apiVersion = cli.ClientVersion()
+	return m, nil
 }
 
-func DeleteImage(imageID string) error {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return err
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
+}
 
-	imagesDeleted, err := cli.ImageRemove(context.Background(), imageID, types.ImageRemoveOptions{Force: true, PruneChildren: true})
+func (m *Manager) DeleteImage(ctx context.Context, imageID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	imagesDeleted, err := m.client.ImageRemove(ctx, imageID, types.ImageRemoveOptions{Force: true, PruneChildren: true})
 	if err != nil {
 		return err
 	}
@@ -84,63 +164,11 @@ func GetImageIDByTag(i Images, inputTag string) (string, error) {
 	return "", ErrImageNotFound
 }
 
-func CreateImage(filePath string, imageName string) error {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return err
-	}
+func (m *Manager) ListImages(ctx context.Context) ([]types.ImageSummary, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	contextName := "context.tar"
-	if err := tarballFolder(contextName, filePath); err != nil {
-		return err
-	}
-	dockerBuildContext, err := os.Open(contextName)
-	if err != nil {
-		return err
-	}
-
-	imageBuildResponse, err := cli.ImageBuild(
-		context.Background(),
-		dockerBuildContext,
-		types.ImageBuildOptions{
-			Context:    dockerBuildContext,
-			Dockerfile: "Dockerfile",
-			Tags:       []string{imageName},
-			Remove:     true})
-	if err != nil {
-		if errContext := dockerBuildContext.Close(); errContext != nil {
-			return errors.Wrap(errors.WithStack(err), errContext.Error())
-		}
-		return err
-	}
-	defer imageBuildResponse.Body.Close()
-
-	result, err := parseResponse(imageBuildResponse.Body)
-	if err != nil {
-		if errContext := dockerBuildContext.Close(); errContext != nil {
-			return errors.Wrap(errors.WithStack(err), errContext.Error())
-		}
-		return err
-	}
-	value, ok := result["errorDetail"]
-	if ok {
-		dockerError := errors.New(value.(map[string]interface{})["message"].(string))
-		if errContext := dockerBuildContext.Close(); errContext != nil {
-			return errors.Wrap(errors.WithStack(dockerError), errContext.Error())
-		}
-		return dockerError
-	}
-
-	return nil
-}
-
-func ListImages() ([]types.ImageSummary, error) {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return nil, err
-	}
-
-	images, err := cli.ImageList(context.Background(), types.ImageListOptions{})
+	images, err := m.client.ImageList(ctx, types.ImageListOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -148,94 +176,77 @@ func ListImages() ([]types.ImageSummary, error) {
 	return images, nil
 }
 
-// CreateNewContainer creates and starts a docker container using an existing image
-// defined by imageName
-func CreateNewContainer(imageName string, address string, port string) (string, error) {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		err = fmt.Errorf("Unable to create docker client: %s", err.Error())
-		return "", err
-	}
-
-	hostBinding := nat.PortBinding{
-		HostIP:   address,
-		HostPort: port,
-	}
-	containerPort, err := nat.NewPort("tcp", port)
-	if err != nil {
-		err = fmt.Errorf("Failed to get port: %s", err.Error())
-		return "", err
-	}
-
-	portBinding := nat.PortMap{containerPort: []nat.PortBinding{hostBinding}}
-	cont, err := cli.ContainerCreate(
-		context.Background(),
-		&container.Config{
-			Image: imageName,
-		},
-		&container.HostConfig{
-			PortBindings: portBinding,
-		}, nil, "")
+// CreateNewContainer creates a docker container using an existing image
+// defined by imageName, publishing port on address.
+func (m *Manager) CreateNewContainer(ctx context.Context, imageName string, address string, port string) (string, error) {
+	created, err := m.CreateContainer(ctx, ContainerSpec{
+		Image:   imageName,
+		Address: address,
+		Port:    port,
+	})
 	if err != nil {
-		err = fmt.Errorf("Failed to create docker container: %s", err.Error())
 		return "", err
 	}
-
-	return cont.ID, nil
+	return created.ID, nil
 }
 
-func DeleteContainer(ID string) error {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return err
-	}
+func (m *Manager) DeleteContainer(ctx context.Context, ID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	if err := cli.ContainerRemove(
-		context.Background(),
+	if err := m.client.ContainerRemove(
+		ctx,
 		ID,
 		types.ContainerRemoveOptions{
 			Force: true,
 		}); err != nil {
 		return err
 	}
+
+	if m.PortAllocator != nil {
+		m.PortAllocator.Release(ID)
+	}
 	return nil
 }
 
-func StartContainer(ID string, networkName string) error {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return err
-	}
+// StartContainer connects the container to the networks and starts it. If a
+// network cannot be connected the container is not started, and if the start
+// fails the networks are disconnected again.
+func (m *Manager) StartContainer(ctx context.Context, ID string, networks []NetworkAttachment) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	if err := cli.ContainerStart(context.Background(), ID, types.ContainerStartOptions{}); err != nil {
-		err = errors.Wrap(errors.WithStack(err), "Failed to start container")
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
 		return err
 	}
 
-	networkID, err := getNetworkID(networkName)
+	networkIDs, err := m.connectNetworks(ctx, containerJSON, networks)
 	if err != nil {
 		return err
 	}
 
-	if err := networkConnect(networkID, ID); err != nil {
+	if err := m.client.ContainerStart(ctx, containerJSON.ID, types.ContainerStartOptions{}); err != nil {
+		err = errors.Wrap(errors.WithStack(err), "Failed to start container")
+		if errRollback := m.disconnectNetworks(containerJSON.ID, networkIDs); errRollback != nil {
+			log.Println(errors.Wrap(errRollback, "Failed to disconnect networks"))
+		}
 		return err
 	}
 
 	return nil
 }
 
-func GetNetworkEndpointResources(networkName string) (map[string]types.EndpointResource, error) {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return nil, err
-	}
+func (m *Manager) GetNetworkEndpointResources(ctx context.Context, networkName string) (map[string]types.EndpointResource, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	networkID, err := getNetworkID(networkName)
+	networkID, err := m.getNetworkID(ctx, networkName)
 	if err != nil {
 		return nil, err
 	}
 
-	network, err := cli.NetworkInspect(context.Background(), networkID)
+	network, err := m.client.NetworkInspect(ctx, networkID, types.NetworkInspectOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -243,18 +254,16 @@ func GetNetworkEndpointResources(networkName string) (map[string]types.EndpointR
 	return network.Containers, nil
 }
 
-func GetIPAddress(containerID string, networkName string) (string, error) {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return "", err
-	}
+func (m *Manager) GetIPAddress(ctx context.Context, containerID string, networkName string) (string, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	networkID, err := getNetworkID(networkName)
+	networkID, err := m.getNetworkID(ctx, networkName)
 	if err != nil {
 		return "", err
 	}
 
-	network, err := cli.NetworkInspect(context.Background(), networkID)
+	network, err := m.client.NetworkInspect(ctx, networkID, types.NetworkInspectOptions{})
 	if err != nil {
 		return "", err
 	}
@@ -268,54 +277,122 @@ func GetIPAddress(containerID string, networkName string) (string, error) {
 	return "", errors.New("Container is not running or not connected to any network")
 }
 
-func StopContainer(ID string) error {
-	cli, err := client.NewEnvClient()
+func (m *Manager) StopContainer(ctx context.Context, ID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if err := m.client.ContainerStop(ctx, ID, container.StopOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PauseContainer suspends the processes of a running container.
+func (m *Manager) PauseContainer(ctx context.Context, ID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
 	if err != nil {
 		return err
 	}
+	if containerJSON.State == nil || !containerJSON.State.Running || containerJSON.State.Paused {
+		return ErrContainerNotRunning
+	}
 
-	if err := cli.ContainerStop(context.Background(), ID, nil); err != nil {
+	if err := m.client.ContainerPause(ctx, ID); err != nil {
 		return err
 	}
 	return nil
 }
 
-func PauseContainer(ID string) error {
-	cli, err := client.NewEnvClient()
+// UnpauseContainer resumes the processes of a paused container.
+func (m *Manager) UnpauseContainer(ctx context.Context, ID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
 	if err != nil {
 		return err
 	}
+	if containerJSON.State == nil || !containerJSON.State.Paused {
+		return ErrContainerNotPaused
+	}
 
-	if err := cli.ContainerPause(context.Background(), ID); err != nil {
+	if err := m.client.ContainerUnpause(ctx, ID); err != nil {
 		return err
 	}
 	return nil
 }
 
-func UnpauseContainer(ID string) error {
-	cli, err := client.NewEnvClient()
-	if err != nil {
+// RestartContainer stops the container, waiting at most timeout before it is
+// killed, and starts it again. A negative timeout uses the daemon default.
+func (m *Manager) RestartContainer(ctx context.Context, ID string, timeout time.Duration) error {
+	options := container.StopOptions{}
+	if timeout >= 0 {
+		seconds := int(timeout.Seconds())
+		options.Timeout = &seconds
+	} else {
+		timeout = 0
+	}
+	// The restart itself may take the whole stop timeout.
+	ctx, cancel := withTimeout(ctx, m.Timeout+timeout)
+	defer cancel()
+
+	if _, err := m.inspectContainer(ctx, ID); err != nil {
 		return err
 	}
 
-	if err := cli.ContainerUnpause(context.Background(), ID); err != nil {
+	if err := m.client.ContainerRestart(ctx, ID, options); err != nil {
 		return err
 	}
 	return nil
 }
 
-func IsContainerRunning(ID string) bool {
+var signalRegexp = regexp.MustCompile(`^((SIG)?[A-Z][A-Z0-9+-]*|[0-9]+)$`)
+
+// KillContainer sends signal, e.g. SIGTERM, TERM or 15, to the main process
+// of a running container. An empty signal sends SIGKILL.
+func (m *Manager) KillContainer(ctx context.Context, ID string, signal string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if signal == "" {
+		signal = "SIGKILL"
+	}
+	if !signalRegexp.MatchString(signal) {
+		return &ValidationError{Field: "signal", Message: "expected a signal name or number"}
+	}
 
-	return true
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return err
+	}
+	if containerJSON.State == nil || !containerJSON.State.Running {
+		return ErrContainerNotRunning
+	}
+
+	if err := m.client.ContainerKill(ctx, ID, signal); err != nil {
+		return err
+	}
+	return nil
 }
 
-func ListContainers() ([]types.Container, error) {
-	cli, err := client.NewEnvClient()
+// IsContainerRunning reports whether the container is running. Paused
+// containers are not considered running.
+func (m *Manager) IsContainerRunning(ctx context.Context, ID string) (bool, error) {
+	details, err := m.InspectContainer(ctx, ID)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
+	return details.State.Running && !details.State.Paused, nil
+}
 
-	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+func (m *Manager) ListContainers(ctx context.Context) ([]types.Container, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true})
 	if err != nil {
 		return nil, err
 	}
@@ -323,13 +400,8 @@ func ListContainers() ([]types.Container, error) {
 	return containers, nil
 }
 
-func getNetworkID(networkName string) (string, error) {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return "", err
-	}
-
-	networks, err := cli.NetworkList(context.Background(), types.NetworkListOptions{})
+func (m *Manager) getNetworkID(ctx context.Context, networkName string) (string, error) {
+	networks, err := m.client.NetworkList(ctx, types.NetworkListOptions{})
 	if err != nil {
 		return "", err
 	}
@@ -340,23 +412,30 @@ func getNetworkID(networkName string) (string, error) {
 		}
 	}
 
-	return "", errors.New("Network not found")
+	return "", ErrNetworkNotFound
 }
 
-func networkConnect(networkID string, containerID string) error {
-	cli, err := client.NewEnvClient()
-	if err != nil {
+func (m *Manager) networkConnect(ctx context.Context, networkID string, containerID string, settings *network.EndpointSettings) error {
+	if err := m.client.NetworkConnect(ctx, networkID, containerID, settings); err != nil {
 		return err
 	}
+	return nil
+}
 
-	if err := cli.NetworkConnect(context.Background(), networkID, containerID, nil); err != nil {
-		return err
+// inspectContainer returns ErrContainerNotFound for unknown containers.
+func (m *Manager) inspectContainer(ctx context.Context, ID string) (types.ContainerJSON, error) {
+	containerJSON, err := m.client.ContainerInspect(ctx, ID)
+	if err != nil && errdefs.IsNotFound(err) {
+		return containerJSON, ErrContainerNotFound
 	}
-	return nil
+	return containerJSON, err
 }
 
-func ContainerExists(ID string) error {
-	containerList, err := ListContainers()
+func (m *Manager) ContainerExists(ctx context.Context, ID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containerList, err := m.ListContainers(ctx)
 	if err != nil {
 		return err
 	}
@@ -370,20 +449,18 @@ func ContainerExists(ID string) error {
 	return ErrContainerNotFound
 }
 
-func StopContainerByImageID(imageID string) error {
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		return err
-	}
+func (m *Manager) StopContainerByImageID(ctx context.Context, imageID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true})
 	if err != nil {
 		return err
 	}
 
 	for _, container := range containers {
 		if container.ImageID == imageID {
-			if err := StopContainer(container.ID); err != nil {
+			if err := m.StopContainer(ctx, container.ID); err != nil {
 				return err
 			}
 		}
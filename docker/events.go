@@ -0,0 +1,353 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	volumetypes "github.com/docker/docker/api/types/volume"
+)
+
+// ManagedLabel marks the containers and images created through the Manager.
+const ManagedLabel = "com.artofimagination.golang-docker.managed"
+
+// managedLabels returns a copy of labels with ManagedLabel set.
+func managedLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels)+1)
+	for key, value := range labels {
+		result[key] = value
+	}
+	result[ManagedLabel] = "true"
+	return result
+}
+
+// Event is a change of a container, image, network or volume reported by the daemon.
+type Event struct {
+	// Type is one of container, image, network or volume.
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	// ID of the object that changed.
+	ID string `json:"id"`
+	// Attributes hold the name, labels and details of the change, e.g. the exitCode of a "die" event.
+	Attributes map[string]string `json:"attributes"`
+	Time       time.Time         `json:"time"`
+}
+
+// EventFilter selects events. Empty lists match everything, otherwise the
+// event has to match one of the entries of every list.
+type EventFilter struct {
+	Types   []string
+	Actions []string
+	// Containers are container IDs or names. Network events match on the container connected or disconnected.
+	Containers []string
+	// Labels are "key" or "key=value" entries that all have to be set on the object.
+	Labels []string
+}
+
+func matchesAny(value string, accepted []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	for _, entry := range accepted {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the event is selected by the filter.
+func (f *EventFilter) Matches(event *Event) bool {
+	// Actions such as "exec_start: sh" carry details after the colon.
+	action := strings.SplitN(event.Action, ":", 2)[0]
+	if !matchesAny(event.Type, f.Types) || !matchesAny(action, f.Actions) {
+		return false
+	}
+
+	if len(f.Containers) > 0 {
+		ID, name := event.ID, event.Attributes["name"]
+		if event.Type != events.ContainerEventType {
+			ID, name = event.Attributes["container"], ""
+		}
+		if !matchesAny(ID, f.Containers) && (name == "" || !matchesAny(name, f.Containers)) {
+			return false
+		}
+	}
+
+	return matchLabels(event.Attributes, f.Labels)
+}
+
+// matchLabels reports whether all "key" or "key=value" entries are set in labels.
+func matchLabels(labels map[string]string, entries []string) bool {
+	for _, entry := range entries {
+		key, value := entry, ""
+		hasValue := false
+		if index := strings.Index(entry, "="); index >= 0 {
+			key, value, hasValue = entry[:index], entry[index+1:], true
+		}
+
+		current, ok := labels[key]
+		if !ok || (hasValue && current != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// eventBufferSize is the number of events queued for a subscriber. Events
+// are dropped for subscribers that fall further behind.
+const eventBufferSize = 64
+
+// EventSubscription receives the events selected by its filter.
+type EventSubscription struct {
+	filter EventFilter
+	events chan Event
+	hub    *EventHub
+}
+
+// Events returns the channel of events. It is closed when the subscription is closed.
+func (s *EventSubscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close ends the subscription.
+func (s *EventSubscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// EventHub reads the daemon events once and distributes the events of the
+// managed objects to any number of subscribers.
+type EventHub struct {
+	manager *Manager
+
+	lock          sync.Mutex
+	subscriptions map[*EventSubscription]bool
+	// containers holds the IDs of the managed containers, network events
+	// only name the container, not its labels.
+	containers map[string]bool
+	// networks and volumes hold the labels of the managed networks by ID and
+	// volumes by name. Their events carry no labels at all.
+	networks map[string]map[string]string
+	volumes  map[string]map[string]string
+}
+
+// NewEventHub creates a hub reading the events of the manager. Run has to be
+// called for the hub to receive events.
+func (m *Manager) NewEventHub() *EventHub {
+	return &EventHub{
+		manager:       m,
+		subscriptions: make(map[*EventSubscription]bool),
+		containers:    make(map[string]bool),
+		networks:      make(map[string]map[string]string),
+		volumes:       make(map[string]map[string]string),
+	}
+}
+
+// Subscribe registers a subscriber for the events selected by filter.
+func (h *EventHub) Subscribe(filter EventFilter) *EventSubscription {
+	subscription := &EventSubscription{
+		filter: filter,
+		events: make(chan Event, eventBufferSize),
+		hub:    h,
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.subscriptions[subscription] = true
+	return subscription
+}
+
+func (h *EventHub) unsubscribe(subscription *EventSubscription) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.subscriptions[subscription] {
+		delete(h.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// loadManaged initializes the managed containers, networks and volumes from the daemon.
+func (h *EventHub) loadManaged(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, h.manager.Timeout)
+	defer cancel()
+
+	managedFilter := filters.NewArgs()
+	managedFilter.Add("label", ManagedLabel)
+	containers, err := h.manager.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: managedFilter})
+	if err != nil {
+		return err
+	}
+	networks, err := h.manager.client.NetworkList(ctx, types.NetworkListOptions{Filters: managedFilter})
+	if err != nil {
+		return err
+	}
+	volumes, err := h.manager.client.VolumeList(ctx, volumetypes.ListOptions{Filters: managedFilter})
+	if err != nil {
+		return err
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.containers = make(map[string]bool, len(containers))
+	for _, container := range containers {
+		h.containers[container.ID] = true
+	}
+	h.networks = make(map[string]map[string]string, len(networks))
+	for _, network := range networks {
+		h.networks[network.ID] = network.Labels
+	}
+	h.volumes = make(map[string]map[string]string, len(volumes.Volumes))
+	for _, volume := range volumes.Volumes {
+		if volume != nil {
+			h.volumes[volume.Name] = volume.Labels
+		}
+	}
+	return nil
+}
+
+// createdLabels returns the labels of a network or volume on its create event,
+// as the daemon does not put them on the event.
+func (h *EventHub) createdLabels(ctx context.Context, event *Event) map[string]string {
+	if event.Action != "create" {
+		return nil
+	}
+
+	ctx, cancel := withTimeout(ctx, h.manager.Timeout)
+	defer cancel()
+	switch event.Type {
+	case events.NetworkEventType:
+		if resource, err := h.manager.client.NetworkInspect(ctx, event.ID, types.NetworkInspectOptions{}); err == nil {
+			return resource.Labels
+		}
+	case events.VolumeEventType:
+		if volume, err := h.manager.client.VolumeInspect(ctx, event.ID); err == nil {
+			return volume.Labels
+		}
+	}
+	return nil
+}
+
+// trackObject records or forgets a managed network or volume and adds its
+// labels to the attributes of the event. It reports whether the object is managed.
+func trackObject(objects map[string]map[string]string, event *Event, created map[string]string) bool {
+	if created[ManagedLabel] == "true" {
+		objects[event.ID] = created
+	}
+	labels, managed := objects[event.ID]
+	if event.Action == "destroy" {
+		delete(objects, event.ID)
+	}
+	for key, value := range labels {
+		if _, ok := event.Attributes[key]; !ok {
+			event.Attributes[key] = value
+		}
+	}
+	return managed
+}
+
+// isManaged reports whether the event concerns a managed object and keeps
+// track of the managed containers, networks and volumes. created holds the
+// labels of networks and volumes looked up on their create event.
+func (h *EventHub) isManaged(event *Event, created map[string]string) bool {
+	switch event.Type {
+	case events.ContainerEventType:
+		managed := event.Attributes[ManagedLabel] == "true"
+		if managed {
+			if event.Action == "destroy" {
+				delete(h.containers, event.ID)
+			} else {
+				h.containers[event.ID] = true
+			}
+		}
+		return managed
+	case events.NetworkEventType:
+		// Connecting a managed container to any network is a managed event.
+		managed := trackObject(h.networks, event, created)
+		return managed || h.containers[event.Attributes["container"]]
+	case events.VolumeEventType:
+		managed := trackObject(h.volumes, event, created)
+		return managed || h.containers[event.Attributes["container"]]
+	default:
+		return event.Attributes[ManagedLabel] == "true"
+	}
+}
+
+func (h *EventHub) publish(ctx context.Context, message events.Message) {
+	event := Event{
+		Type:       message.Type,
+		Action:     message.Action,
+		ID:         message.Actor.ID,
+		Attributes: message.Actor.Attributes,
+		Time:       time.Unix(0, message.TimeNano).UTC(),
+	}
+	if event.Attributes == nil {
+		event.Attributes = map[string]string{}
+	}
+	created := h.createdLabels(ctx, &event)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.isManaged(&event, created) {
+		return
+	}
+	for subscription := range h.subscriptions {
+		if !subscription.filter.Matches(&event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			log.Printf("Event subscriber is too slow, dropped %s %s event", event.Type, event.Action)
+		}
+	}
+}
+
+// eventReconnectDelay is the delay before the event stream is reopened after an error.
+const eventReconnectDelay = 2 * time.Second
+
+// Run reads the daemon events and distributes them until ctx is done. The
+// event stream is reopened if it fails, e.g. when the daemon restarts.
+func (h *EventHub) Run(ctx context.Context) {
+	since := ""
+	for {
+		if err := h.loadManaged(ctx); err != nil {
+			log.Printf("Failed to list managed objects: %s", err.Error())
+		}
+
+		messages, errs := h.manager.client.Events(ctx, types.EventsOptions{Since: since})
+		err := h.read(ctx, messages, errs, &since)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Docker event stream failed, reconnecting: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventReconnectDelay):
+		}
+	}
+}
+
+// read distributes the events of a single event stream until it fails.
+// since is updated with the time of the last event, so reconnecting does not lose events.
+func (h *EventHub) read(ctx context.Context, messages <-chan events.Message, errs <-chan error, since *string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case message := <-messages:
+			h.publish(ctx, message)
+			next := message.TimeNano + 1
+			*since = fmt.Sprintf("%d.%09d", next/int64(time.Second), next%int64(time.Second))
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ExecOptions describes a command run inside a running container.
+type ExecOptions struct {
+	Cmd        []string `json:"cmd"`
+	Env        []string `json:"env"`
+	User       string   `json:"user"`
+	Privileged bool     `json:"privileged"`
+	// Tty allocates a terminal, stdout and stderr are then merged.
+	Tty bool `json:"tty"`
+}
+
+// Validate checks the options and returns a *ValidationError naming the first invalid field.
+func (o *ExecOptions) Validate() error {
+	if len(o.Cmd) == 0 || o.Cmd[0] == "" {
+		return &ValidationError{Field: "cmd", Message: "missing"}
+	}
+	for i, variable := range o.Env {
+		if !envNameRegexp.MatchString(strings.SplitN(variable, "=", 2)[0]) {
+			return &ValidationError{Field: fmt.Sprintf("env[%d]", i), Message: "expected KEY=value"}
+		}
+	}
+	return nil
+}
+
+// ExecResult holds the output and exit code of a finished command.
+type ExecResult struct {
+	ExitCode int    `json:"exit-code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// execPollInterval is the delay between checks for the exit code of a command
+// whose output has already ended.
+const execPollInterval = 100 * time.Millisecond
+
+// createExec checks that the container is running and creates the exec instance.
+func (m *Manager) createExec(ctx context.Context, ID string, options ExecOptions, stdin bool) (string, types.ExecConfig, error) {
+	config := types.ExecConfig{
+		User:         options.User,
+		Privileged:   options.Privileged,
+		Tty:          options.Tty,
+		AttachStdin:  stdin,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          options.Env,
+		Cmd:          options.Cmd,
+	}
+	if err := options.Validate(); err != nil {
+		return "", config, err
+	}
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return "", config, err
+	}
+	if containerJSON.State == nil || !containerJSON.State.Running || containerJSON.State.Paused {
+		return "", config, ErrContainerNotRunning
+	}
+
+	response, err := m.client.ContainerExecCreate(ctx, ID, config)
+	if err != nil {
+		return "", config, err
+	}
+	return response.ID, config, nil
+}
+
+// exitCode waits for the command to finish and returns its exit code.
+func (m *Manager) exitCode(ctx context.Context, execID string) (int, error) {
+	for {
+		inspect, err := m.client.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, err
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(execPollInterval):
+		}
+	}
+}
+
+// Exec runs the command in the running container ID and returns its output
+// once it finishes. Stopped or paused containers result in ErrContainerNotRunning.
+func (m *Manager) Exec(ctx context.Context, ID string, options ExecOptions) (*ExecResult, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	execID, config, err := m.createExec(ctx, ID, options, false)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.client.ContainerExecAttach(ctx, execID, types.ExecStartCheck{Tty: config.Tty})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Close()
+	// The hijacked connection does not follow ctx by itself.
+	go func() {
+		<-ctx.Done()
+		response.Close()
+	}()
+
+	var stdout, stderr bytes.Buffer
+	if options.Tty {
+		_, err = io.Copy(&stdout, response.Reader)
+	} else {
+		err = readFrames(response.Reader, func(stream string, payload []byte) error {
+			if stream == "stderr" {
+				_, err := stderr.Write(payload)
+				return err
+			}
+			_, err := stdout.Write(payload)
+			return err
+		})
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	exitCode, err := m.exitCode(ctx, execID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecResult{
+		ExitCode: exitCode,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	}, nil
+}
+
+// ExecSession is a command running in a container with stdin attached.
+// It has to be closed by the caller.
+type ExecSession struct {
+	ID       string
+	manager  *Manager
+	response types.HijackedResponse
+	tty      bool
+}
+
+// StartExec starts the command in the running container ID with stdin, stdout
+// and stderr attached. The session is not bound to the timeout of the manager.
+func (m *Manager) StartExec(ctx context.Context, ID string, options ExecOptions) (*ExecSession, error) {
+	createCtx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	execID, config, err := m.createExec(createCtx, ID, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.client.ContainerExecAttach(ctx, execID, types.ExecStartCheck{Tty: config.Tty})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecSession{
+		ID:       execID,
+		manager:  m,
+		response: response,
+		tty:      options.Tty,
+	}, nil
+}
+
+// Write sends p to the stdin of the command.
+func (s *ExecSession) Write(p []byte) (int, error) {
+	return s.response.Conn.Write(p)
+}
+
+// CloseStdin closes the stdin of the command, the output stays readable.
+func (s *ExecSession) CloseStdin() error {
+	return s.response.CloseWrite()
+}
+
+// ReadOutput passes the output of the command to handler as it arrives, until
+// the command exits, the session is closed or handler returns an error.
+// With a TTY all output is reported as stdout.
+func (s *ExecSession) ReadOutput(handler func(stream string, data []byte) error) error {
+	if !s.tty {
+		return readFrames(s.response.Reader, handler)
+	}
+
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := s.response.Reader.Read(buffer)
+		if n > 0 {
+			if errHandler := handler("stdout", buffer[:n]); errHandler != nil {
+				return errHandler
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Resize changes the terminal size of a session started with a TTY.
+func (s *ExecSession) Resize(ctx context.Context, width uint, height uint) error {
+	ctx, cancel := withTimeout(ctx, s.manager.Timeout)
+	defer cancel()
+
+	if !s.tty {
+		return &ValidationError{Field: "tty", Message: "resize needs a session started with a TTY"}
+	}
+	return s.manager.client.ContainerExecResize(ctx, s.ID, types.ResizeOptions{Width: width, Height: height})
+}
+
+// ExitCode waits for the command to finish and returns its exit code.
+func (s *ExecSession) ExitCode(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.manager.Timeout)
+	defer cancel()
+
+	return s.manager.exitCode(ctx, s.ID)
+}
+
+// Close detaches from the command. The command itself keeps running until
+// its stdin is closed or it exits.
+func (s *ExecSession) Close() {
+	s.response.Close()
+}
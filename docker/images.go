@@ -0,0 +1,178 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// cancelReadCloser releases the context of a streamed response when it is closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// SaveImages returns the images as a docker-save tarball.
+// It is up to the caller to close the returned reader.
+func (m *Manager) SaveImages(ctx context.Context, images []string) (io.ReadCloser, error) {
+	if len(images) == 0 {
+		return nil, errors.New("No images to save")
+	}
+
+	ctx, cancel := withTimeout(ctx, m.TransferTimeout)
+	reader, err := m.client.ImageSave(ctx, images)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelReadCloser{ReadCloser: reader, cancel: cancel}, nil
+}
+
+// LoadResult lists the images loaded from a tarball.
+type LoadResult struct {
+	// Tags holds the loaded tags, ImageIDs the loaded images that have no tag.
+	Tags     []string `json:"tags"`
+	ImageIDs []string `json:"image-ids"`
+}
+
+// LoadImages loads the images of a docker-save tarball.
+func (m *Manager) LoadImages(ctx context.Context, input io.Reader) (*LoadResult, error) {
+	ctx, cancel := withTimeout(ctx, m.TransferTimeout)
+	defer cancel()
+
+	response, err := m.client.ImageLoad(ctx, input, true)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if !response.JSON {
+		return nil, errors.New("Unexpected image load response")
+	}
+
+	result := &LoadResult{
+		Tags:     []string{},
+		ImageIDs: []string{},
+	}
+	err = readJSONMessages(response.Body, func(message JSONMessage) error {
+		line := strings.TrimSpace(message.Stream)
+		switch {
+		case strings.HasPrefix(line, "Loaded image ID: "):
+			result.ImageIDs = append(result.ImageIDs, strings.TrimPrefix(line, "Loaded image ID: "))
+		case strings.HasPrefix(line, "Loaded image: "):
+			result.Tags = append(result.Tags, strings.TrimPrefix(line, "Loaded image: "))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ImageConfig is the runtime configuration stored in an image.
+type ImageConfig struct {
+	Env          []string          `json:"env"`
+	Entrypoint   []string          `json:"entrypoint"`
+	Cmd          []string          `json:"cmd"`
+	WorkingDir   string            `json:"working-dir"`
+	User         string            `json:"user"`
+	ExposedPorts []string          `json:"exposed-ports"`
+	Labels       map[string]string `json:"labels"`
+}
+
+// ImageLayer is a single entry of the image history, newest first.
+type ImageLayer struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"created-by"`
+	Tags      []string  `json:"tags"`
+	Size      int64     `json:"size"`
+	Comment   string    `json:"comment"`
+}
+
+// ImageDetails describes an image and its layer history.
+type ImageDetails struct {
+	ID           string       `json:"id"`
+	RepoTags     []string     `json:"repo-tags"`
+	RepoDigests  []string     `json:"repo-digests"`
+	Created      string       `json:"created"`
+	Size         int64        `json:"size"`
+	Architecture string       `json:"architecture"`
+	Os           string       `json:"os"`
+	Config       ImageConfig  `json:"config"`
+	Layers       []string     `json:"layers"`
+	History      []ImageLayer `json:"history"`
+}
+
+// InspectImage returns the configuration and history of the image reference.
+// ErrImageNotFound is returned for unknown images.
+func (m *Manager) InspectImage(ctx context.Context, reference string) (*ImageDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	image, _, err := m.client.ImageInspectWithRaw(ctx, reference)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, ErrImageNotFound
+		}
+		return nil, err
+	}
+
+	history, err := m.client.ImageHistory(ctx, image.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &ImageDetails{
+		ID:           image.ID,
+		RepoTags:     image.RepoTags,
+		RepoDigests:  image.RepoDigests,
+		Created:      image.Created,
+		Size:         image.Size,
+		Architecture: image.Architecture,
+		Os:           image.Os,
+		Layers:       image.RootFS.Layers,
+		History:      make([]ImageLayer, 0, len(history)),
+	}
+
+	if image.Config != nil {
+		details.Config = ImageConfig{
+			Env:        image.Config.Env,
+			Entrypoint: image.Config.Entrypoint,
+			Cmd:        image.Config.Cmd,
+			WorkingDir: image.Config.WorkingDir,
+			User:       image.Config.User,
+			Labels:     image.Config.Labels,
+		}
+		for port := range image.Config.ExposedPorts {
+			details.Config.ExposedPorts = append(details.Config.ExposedPorts, string(port))
+		}
+		sort.Strings(details.Config.ExposedPorts)
+	}
+
+	for _, layer := range history {
+		details.History = append(details.History, ImageLayer{
+			ID:        layer.ID,
+			Created:   time.Unix(layer.Created, 0).UTC(),
+			CreatedBy: layer.CreatedBy,
+			Tags:      layer.Tags,
+			Size:      layer.Size,
+			Comment:   layer.Comment,
+		})
+	}
+
+	return details, nil
+}
@@ -0,0 +1,214 @@
+package docker
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// HealthCheck is the result of a single health check run.
+type HealthCheck struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exit-code"`
+	Output   string    `json:"output"`
+}
+
+// ContainerHealth is the health check status of a container.
+type ContainerHealth struct {
+	// Status is one of starting, healthy or unhealthy.
+	Status        string        `json:"status"`
+	FailingStreak int           `json:"failing-streak"`
+	Log           []HealthCheck `json:"log"`
+}
+
+// ContainerState is the runtime state of a container.
+type ContainerState struct {
+	// Status is one of created, running, paused, restarting, removing, exited or dead.
+	Status     string `json:"status"`
+	Running    bool   `json:"running"`
+	Paused     bool   `json:"paused"`
+	Restarting bool   `json:"restarting"`
+	OOMKilled  bool   `json:"oom-killed"`
+	Dead       bool   `json:"dead"`
+	Pid        int    `json:"pid"`
+	ExitCode   int    `json:"exit-code"`
+	Error      string `json:"error"`
+	// StartedAt and FinishedAt are zero if the container never started or finished.
+	StartedAt  time.Time `json:"started-at"`
+	FinishedAt time.Time `json:"finished-at"`
+	// Health is only set for containers with a health check.
+	Health *ContainerHealth `json:"health,omitempty"`
+}
+
+// ContainerConfig is the configuration a container was created with.
+type ContainerConfig struct {
+	Image        string            `json:"image"`
+	Env          []string          `json:"env"`
+	Cmd          []string          `json:"cmd"`
+	Entrypoint   []string          `json:"entrypoint"`
+	WorkingDir   string            `json:"working-dir"`
+	User         string            `json:"user"`
+	Tty          bool              `json:"tty"`
+	ExposedPorts []string          `json:"exposed-ports"`
+	Labels       map[string]string `json:"labels"`
+}
+
+// ContainerEndpoint is the attachment of a container to a network.
+type ContainerEndpoint struct {
+	NetworkID  string   `json:"network-id"`
+	IPAddress  string   `json:"ip-address"`
+	Gateway    string   `json:"gateway"`
+	MacAddress string   `json:"mac-address"`
+	Aliases    []string `json:"aliases"`
+}
+
+// ContainerNetworkSettings lists the published ports and networks of a container.
+type ContainerNetworkSettings struct {
+	Ports []PortMapping `json:"ports"`
+	// Networks are keyed by network name.
+	Networks map[string]ContainerEndpoint `json:"networks"`
+}
+
+// ContainerDetails describes a container.
+type ContainerDetails struct {
+	ID              string                   `json:"id"`
+	Name            string                   `json:"name"`
+	ImageID         string                   `json:"image-id"`
+	Created         time.Time                `json:"created"`
+	RestartCount    int                      `json:"restart-count"`
+	State           ContainerState           `json:"state"`
+	Config          ContainerConfig          `json:"config"`
+	NetworkSettings ContainerNetworkSettings `json:"network-settings"`
+	Mounts          []MountSpec              `json:"mounts"`
+}
+
+// parseDockerTime parses the timestamps of the inspect output. The zero time
+// the daemon reports for events that did not happen yet is left zero.
+func parseDockerTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
+
+func containerState(state *types.ContainerState) ContainerState {
+	if state == nil {
+		return ContainerState{}
+	}
+
+	result := ContainerState{
+		Status:     state.Status,
+		Running:    state.Running,
+		Paused:     state.Paused,
+		Restarting: state.Restarting,
+		OOMKilled:  state.OOMKilled,
+		Dead:       state.Dead,
+		Pid:        state.Pid,
+		ExitCode:   state.ExitCode,
+		Error:      state.Error,
+		StartedAt:  parseDockerTime(state.StartedAt),
+		FinishedAt: parseDockerTime(state.FinishedAt),
+	}
+	if state.Health != nil {
+		result.Health = &ContainerHealth{
+			Status:        state.Health.Status,
+			FailingStreak: state.Health.FailingStreak,
+			Log:           []HealthCheck{},
+		}
+		for _, check := range state.Health.Log {
+			result.Health.Log = append(result.Health.Log, HealthCheck{
+				Start:    check.Start,
+				End:      check.End,
+				ExitCode: check.ExitCode,
+				Output:   check.Output,
+			})
+		}
+	}
+	return result
+}
+
+func containerDetails(containerJSON *types.ContainerJSON) (*ContainerDetails, error) {
+	details := &ContainerDetails{
+		ID:           containerJSON.ID,
+		Name:         strings.TrimPrefix(containerJSON.Name, "/"),
+		ImageID:      containerJSON.Image,
+		Created:      parseDockerTime(containerJSON.Created),
+		RestartCount: containerJSON.RestartCount,
+		State:        containerState(containerJSON.State),
+		NetworkSettings: ContainerNetworkSettings{
+			Networks: map[string]ContainerEndpoint{},
+		},
+		Mounts: []MountSpec{},
+	}
+
+	if config := containerJSON.Config; config != nil {
+		details.Config = ContainerConfig{
+			Image:        config.Image,
+			Env:          config.Env,
+			Cmd:          config.Cmd,
+			Entrypoint:   config.Entrypoint,
+			WorkingDir:   config.WorkingDir,
+			User:         config.User,
+			Tty:          config.Tty,
+			ExposedPorts: []string{},
+			Labels:       config.Labels,
+		}
+		for port := range config.ExposedPorts {
+			details.Config.ExposedPorts = append(details.Config.ExposedPorts, string(port))
+		}
+		sort.Strings(details.Config.ExposedPorts)
+	}
+
+	var err error
+	details.NetworkSettings.Ports, err = boundPorts(containerJSON.NetworkSettings)
+	if err != nil {
+		return nil, err
+	}
+	if containerJSON.NetworkSettings != nil {
+		for name, endpoint := range containerJSON.NetworkSettings.Networks {
+			if endpoint == nil {
+				continue
+			}
+			details.NetworkSettings.Networks[name] = ContainerEndpoint{
+				NetworkID:  endpoint.NetworkID,
+				IPAddress:  endpoint.IPAddress,
+				Gateway:    endpoint.Gateway,
+				MacAddress: endpoint.MacAddress,
+				Aliases:    endpoint.Aliases,
+			}
+		}
+	}
+
+	for _, mount := range containerJSON.Mounts {
+		source := mount.Source
+		if mount.Type == "volume" {
+			source = mount.Name
+		}
+		details.Mounts = append(details.Mounts, MountSpec{
+			Type:     string(mount.Type),
+			Source:   source,
+			Target:   mount.Destination,
+			ReadOnly: !mount.RW,
+		})
+	}
+
+	return details, nil
+}
+
+// InspectContainer returns the state, configuration and network settings of
+// the container. ErrContainerNotFound is returned for unknown containers.
+func (m *Manager) InspectContainer(ctx context.Context, ID string) (*ContainerDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	return containerDetails(&containerJSON)
+}
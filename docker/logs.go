@@ -0,0 +1,214 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+// LogOptions selects the container output returned by ContainerLogs.
+type LogOptions struct {
+	Stdout bool
+	Stderr bool
+	// Since and Until are RFC 3339 times, unix timestamps or durations
+	// relative to now, e.g. 10m.
+	Since string
+	Until string
+	// Tail is the number of lines returned from the end of the logs, or "all".
+	Tail       string
+	Timestamps bool
+	// Follow keeps streaming new output until the context is done or Until is reached.
+	Follow bool
+}
+
+// LogLine is a single line of container output.
+type LogLine struct {
+	// Stream is stdout or stderr.
+	Stream    string `json:"stream"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Text      string `json:"text"`
+}
+
+// LogHandler receives the log lines of a container. Returning an error stops the logs.
+type LogHandler func(line LogLine) error
+
+// parseLogTime parses a time given as RFC 3339, unix timestamp or duration before now.
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	return time.Time{}, errors.New("Invalid time " + value)
+}
+
+// LogReader delivers the log lines of a container. It has to be closed by the caller.
+type LogReader struct {
+	reader     io.ReadCloser
+	tty        bool
+	timestamps bool
+}
+
+// ContainerLogs opens the output of the container. Invalid options result in a
+// *ValidationError, unknown containers in ErrContainerNotFound.
+// Without Follow the reader ends with the current logs, otherwise it ends when
+// ctx is done, Until is reached or the container stops.
+func (m *Manager) ContainerLogs(ctx context.Context, ID string, options LogOptions) (*LogReader, error) {
+	cancel := context.CancelFunc(func() {})
+	if !options.Follow {
+		ctx, cancel = withTimeout(ctx, m.Timeout)
+	}
+
+	logs, err := m.openLogs(ctx, ID, options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	logs.reader = &cancelReadCloser{ReadCloser: logs.reader, cancel: cancel}
+	return logs, nil
+}
+
+func (m *Manager) openLogs(ctx context.Context, ID string, options LogOptions) (*LogReader, error) {
+	now := time.Now()
+	since := ""
+	if options.Since != "" {
+		t, err := parseLogTime(options.Since, now)
+		if err != nil {
+			return nil, &ValidationError{Field: "since", Message: err.Error()}
+		}
+		since = strconv.FormatInt(t.Unix(), 10)
+	}
+	until := ""
+	if options.Until != "" {
+		t, err := parseLogTime(options.Until, now)
+		if err != nil {
+			return nil, &ValidationError{Field: "until", Message: err.Error()}
+		}
+		until = strconv.FormatInt(t.Unix(), 10)
+	}
+	logs := &LogReader{timestamps: options.Timestamps}
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	logs.tty = containerJSON.Config != nil && containerJSON.Config.Tty
+
+	// A followed stream ends once until is reached, even without new output.
+	logs.reader, err = m.client.ContainerLogs(ctx, ID, types.ContainerLogsOptions{
+		ShowStdout: options.Stdout,
+		ShowStderr: options.Stderr,
+		Since:      since,
+		Until:      until,
+		Timestamps: options.Timestamps,
+		Follow:     options.Follow,
+		Tail:       options.Tail,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// ReadLines passes the log lines to handler until the logs end or handler returns an error.
+func (l *LogReader) ReadLines(handler LogHandler) error {
+	return readLogLines(l.reader, l.tty, func(stream string, text string) error {
+		line := LogLine{Stream: stream, Text: text}
+		if l.timestamps {
+			if index := strings.IndexByte(text, ' '); index > 0 {
+				line.Timestamp, line.Text = text[:index], text[index+1:]
+			}
+		}
+		return handler(line)
+	})
+}
+
+// Close stops reading the logs.
+func (l *LogReader) Close() error {
+	return l.reader.Close()
+}
+
+// readLogLines splits the log stream into lines. Unless the container has a
+// TTY the stream is multiplexed, see readFrames.
+func readLogLines(reader io.Reader, tty bool, emit func(stream string, text string) error) error {
+	if tty {
+		buffered := bufio.NewReader(reader)
+		for {
+			text, err := buffered.ReadString('\n')
+			if text != "" {
+				if errEmit := emit("stdout", strings.TrimSuffix(text, "\n")); errEmit != nil {
+					return errEmit
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	pending := map[string]string{}
+	err := readFrames(reader, func(stream string, payload []byte) error {
+		lines := strings.Split(pending[stream]+string(payload), "\n")
+		pending[stream] = lines[len(lines)-1]
+		for _, text := range lines[:len(lines)-1] {
+			if err := emit(stream, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range []string{"stdout", "stderr"} {
+		if pending[stream] != "" {
+			if err := emit(stream, pending[stream]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readFrames demultiplexes the output of a container attached without a TTY.
+// Every frame starts with an 8 byte header holding the stream type and the
+// payload size. It returns nil at the end of the stream.
+func readFrames(reader io.Reader, emit func(stream string, payload []byte) error) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		stream := "stdout"
+		if header[0] == 2 {
+			stream = "stderr"
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return err
+		}
+
+		if err := emit(stream, payload); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+var ErrNetworkNotFound = errors.New("Network not found")
+var ErrNetworkExists = errors.New("Network already exists")
+var ErrNetworkInUse = errors.New("Network has containers connected")
+var ErrNotConnected = errors.New("Container is not connected to the network")
+
+// IPAMConfig is an address pool of a network.
+type IPAMConfig struct {
+	// Subnet in CIDR notation, e.g. 172.28.0.0/16 or fd00:28::/64.
+	Subnet string `json:"subnet"`
+	// Gateway has to be inside Subnet, the daemon picks one if it is empty.
+	Gateway string `json:"gateway,omitempty"`
+	// IPRange limits the addresses given to containers to a part of Subnet.
+	IPRange string `json:"ip-range,omitempty"`
+}
+
+// NetworkSpec describes a network to create.
+type NetworkSpec struct {
+	Name string `json:"name"`
+	// Driver defaults to bridge.
+	Driver     string            `json:"driver"`
+	IPAM       []IPAMConfig      `json:"ipam"`
+	Labels     map[string]string `json:"labels"`
+	Internal   bool              `json:"internal"`
+	EnableIPv6 bool              `json:"enable-ipv6"`
+	// Options are passed to the driver.
+	Options map[string]string `json:"options"`
+}
+
+var networkNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Validate checks the spec and returns a *ValidationError naming the first invalid field.
+func (s *NetworkSpec) Validate() error {
+	if s.Name == "" {
+		return &ValidationError{Field: "name", Message: "missing"}
+	}
+	if !networkNameRegexp.MatchString(s.Name) {
+		return &ValidationError{Field: "name", Message: "only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed"}
+	}
+
+	for i, pool := range s.IPAM {
+		field := fmt.Sprintf("ipam[%d]", i)
+		_, subnet, err := net.ParseCIDR(pool.Subnet)
+		if err != nil {
+			return &ValidationError{Field: field + ".subnet", Message: "expected an address in CIDR notation"}
+		}
+		if subnet.IP.To4() == nil && !s.EnableIPv6 {
+			return &ValidationError{Field: field + ".subnet", Message: "IPv6 subnets need enable-ipv6"}
+		}
+		if pool.Gateway != "" {
+			gateway := net.ParseIP(pool.Gateway)
+			if gateway == nil {
+				return &ValidationError{Field: field + ".gateway", Message: "not an IP address"}
+			}
+			if !subnet.Contains(gateway) {
+				return &ValidationError{Field: field + ".gateway", Message: "not inside the subnet"}
+			}
+		}
+		if pool.IPRange != "" {
+			rangeIP, ipRange, err := net.ParseCIDR(pool.IPRange)
+			if err != nil {
+				return &ValidationError{Field: field + ".ip-range", Message: "expected an address in CIDR notation"}
+			}
+			rangeSize, _ := ipRange.Mask.Size()
+			subnetSize, _ := subnet.Mask.Size()
+			if !subnet.Contains(rangeIP) || rangeSize < subnetSize {
+				return &ValidationError{Field: field + ".ip-range", Message: "not inside the subnet"}
+			}
+		}
+	}
+	return nil
+}
+
+// NetworkEndpoint is a container attached to a network.
+type NetworkEndpoint struct {
+	Name        string `json:"name"`
+	EndpointID  string `json:"endpoint-id"`
+	MacAddress  string `json:"mac-address"`
+	IPv4Address string `json:"ipv4-address"`
+	IPv6Address string `json:"ipv6-address"`
+}
+
+// NetworkDetails describes a network.
+type NetworkDetails struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Scope      string            `json:"scope"`
+	Created    time.Time         `json:"created"`
+	Internal   bool              `json:"internal"`
+	EnableIPv6 bool              `json:"enable-ipv6"`
+	IPAM       []IPAMConfig      `json:"ipam"`
+	Labels     map[string]string `json:"labels"`
+	Options    map[string]string `json:"options"`
+	// Containers are keyed by container ID. Only inspecting a network lists them.
+	Containers map[string]NetworkEndpoint `json:"containers,omitempty"`
+}
+
+func networkDetails(resource *types.NetworkResource) *NetworkDetails {
+	details := &NetworkDetails{
+		ID:         resource.ID,
+		Name:       resource.Name,
+		Driver:     resource.Driver,
+		Scope:      resource.Scope,
+		Created:    resource.Created,
+		Internal:   resource.Internal,
+		EnableIPv6: resource.EnableIPv6,
+		IPAM:       []IPAMConfig{},
+		Labels:     resource.Labels,
+		Options:    resource.Options,
+	}
+	for _, pool := range resource.IPAM.Config {
+		details.IPAM = append(details.IPAM, IPAMConfig{Subnet: pool.Subnet, Gateway: pool.Gateway, IPRange: pool.IPRange})
+	}
+	if resource.Containers != nil {
+		details.Containers = make(map[string]NetworkEndpoint, len(resource.Containers))
+		for ID, endpoint := range resource.Containers {
+			details.Containers[ID] = NetworkEndpoint{
+				Name:        endpoint.Name,
+				EndpointID:  endpoint.EndpointID,
+				MacAddress:  endpoint.MacAddress,
+				IPv4Address: endpoint.IPv4Address,
+				IPv6Address: endpoint.IPv6Address,
+			}
+		}
+	}
+	return details
+}
+
+// inspectNetwork returns ErrNetworkNotFound for unknown networks.
+func (m *Manager) inspectNetwork(ctx context.Context, nameOrID string) (types.NetworkResource, error) {
+	resource, err := m.client.NetworkInspect(ctx, nameOrID, types.NetworkInspectOptions{})
+	if err != nil && errdefs.IsNotFound(err) {
+		return resource, ErrNetworkNotFound
+	}
+	return resource, err
+}
+
+// CreateNetwork validates spec and creates the network it describes.
+func (m *Manager) CreateNetwork(ctx context.Context, spec NetworkSpec) (*NetworkDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := m.getNetworkID(ctx, spec.Name); err == nil {
+		return nil, ErrNetworkExists
+	} else if err != ErrNetworkNotFound {
+		return nil, err
+	}
+
+	options := types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         spec.Driver,
+		EnableIPv6:     spec.EnableIPv6,
+		Internal:       spec.Internal,
+		Options:        spec.Options,
+		Labels:         managedLabels(spec.Labels),
+	}
+	if len(spec.IPAM) > 0 {
+		options.IPAM = &network.IPAM{}
+		for _, pool := range spec.IPAM {
+			options.IPAM.Config = append(options.IPAM.Config, network.IPAMConfig{
+				Subnet:  pool.Subnet,
+				Gateway: pool.Gateway,
+				IPRange: pool.IPRange,
+			})
+		}
+	}
+
+	response, err := m.client.NetworkCreate(ctx, spec.Name, options)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create network: %s", err.Error())
+	}
+
+	resource, err := m.inspectNetwork(ctx, response.ID)
+	if err != nil {
+		return nil, err
+	}
+	return networkDetails(&resource), nil
+}
+
+// RemoveNetwork removes the network. Networks with containers attached cannot be removed.
+func (m *Manager) RemoveNetwork(ctx context.Context, nameOrID string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	resource, err := m.inspectNetwork(ctx, nameOrID)
+	if err != nil {
+		return err
+	}
+	if len(resource.Containers) > 0 {
+		return ErrNetworkInUse
+	}
+
+	return m.client.NetworkRemove(ctx, resource.ID)
+}
+
+// ListNetworks returns all networks sorted by name, without their containers.
+func (m *Manager) ListNetworks(ctx context.Context) ([]NetworkDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	resources, err := m.client.NetworkList(ctx, types.NetworkListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	networks := make([]NetworkDetails, 0, len(resources))
+	for i := range resources {
+		details := networkDetails(&resources[i])
+		details.Containers = nil
+		networks = append(networks, *details)
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Name < networks[j].Name
+	})
+	return networks, nil
+}
+
+// InspectNetwork returns the configuration and the attached containers of the network.
+func (m *Manager) InspectNetwork(ctx context.Context, nameOrID string) (*NetworkDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	resource, err := m.inspectNetwork(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	details := networkDetails(&resource)
+	if details.Containers == nil {
+		details.Containers = map[string]NetworkEndpoint{}
+	}
+	return details, nil
+}
+
+// DisconnectNetwork detaches the container from the network. force also
+// disconnects containers that are not running.
+func (m *Manager) DisconnectNetwork(ctx context.Context, nameOrID string, containerID string, force bool) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containerJSON, err := m.inspectContainer(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	resource, err := m.inspectNetwork(ctx, nameOrID)
+	if err != nil {
+		return err
+	}
+	if containerJSON.NetworkSettings == nil {
+		return ErrNotConnected
+	}
+	if _, ok := containerJSON.NetworkSettings.Networks[resource.Name]; !ok {
+		return ErrNotConnected
+	}
+
+	return m.client.NetworkDisconnect(ctx, resource.ID, containerJSON.ID, force)
+}
+
+var ErrAlreadyConnected = errors.New("Container is already connected to the network")
+
+// NetworkAttachment connects a container to a network.
+type NetworkAttachment struct {
+	Network string `json:"network"`
+	// Aliases are additional names of the container in the DNS of the network.
+	Aliases []string `json:"aliases"`
+	// IPv4Address and IPv6Address request static addresses, which only
+	// networks created with a subnet support.
+	IPv4Address string `json:"ipv4-address"`
+	IPv6Address string `json:"ipv6-address"`
+	// Links are container[:alias] entries, resolvable from the container.
+	Links []string `json:"links"`
+}
+
+var linkRegexp = regexp.MustCompile(`^/?[a-zA-Z0-9][a-zA-Z0-9_.-]*(:[a-zA-Z0-9][a-zA-Z0-9_.-]*)?$`)
+
+// Validate checks the attachment, field is the name of the attachment in errors.
+func (a *NetworkAttachment) Validate(field string) error {
+	if a.Network == "" {
+		return &ValidationError{Field: field + ".network", Message: "missing"}
+	}
+	for _, alias := range a.Aliases {
+		if !networkNameRegexp.MatchString(alias) {
+			return &ValidationError{Field: field + ".aliases", Message: fmt.Sprintf("invalid alias %q", alias)}
+		}
+	}
+	if a.IPv4Address != "" {
+		if ip := net.ParseIP(a.IPv4Address); ip == nil || ip.To4() == nil {
+			return &ValidationError{Field: field + ".ipv4-address", Message: "not an IPv4 address"}
+		}
+	}
+	if a.IPv6Address != "" {
+		if ip := net.ParseIP(a.IPv6Address); ip == nil || ip.To4() != nil {
+			return &ValidationError{Field: field + ".ipv6-address", Message: "not an IPv6 address"}
+		}
+	}
+	for _, link := range a.Links {
+		if !linkRegexp.MatchString(link) {
+			return &ValidationError{Field: field + ".links", Message: fmt.Sprintf("invalid link %q, expected container[:alias]", link)}
+		}
+	}
+	return nil
+}
+
+func (a *NetworkAttachment) endpointSettings() *network.EndpointSettings {
+	settings := &network.EndpointSettings{
+		Aliases: a.Aliases,
+		Links:   a.Links,
+	}
+	if a.IPv4Address != "" || a.IPv6Address != "" {
+		settings.IPAMConfig = &network.EndpointIPAMConfig{
+			IPv4Address: a.IPv4Address,
+			IPv6Address: a.IPv6Address,
+		}
+	}
+	return settings
+}
+
+// NetworkConnectError reports the network that failed to connect. Networks
+// connected before it have been disconnected again.
+type NetworkConnectError struct {
+	Network string
+	Err     error
+	// RollbackErr is set if not all of the previous networks could be disconnected.
+	RollbackErr error
+}
+
+func (e *NetworkConnectError) Error() string {
+	message := fmt.Sprintf("Failed to connect to network %s: %s", e.Network, e.Err.Error())
+	if e.RollbackErr != nil {
+		message += fmt.Sprintf(" (rollback failed: %s)", e.RollbackErr.Error())
+	}
+	return message
+}
+
+// connectNetworks connects the container to all networks or to none of them.
+// Everything that can be checked up front is checked before the first connect.
+func (m *Manager) connectNetworks(ctx context.Context, containerJSON types.ContainerJSON, attachments []NetworkAttachment) ([]string, error) {
+	networkIDs := make([]string, len(attachments))
+	seen := make(map[string]bool, len(attachments))
+	for i := range attachments {
+		field := fmt.Sprintf("networks[%d]", i)
+		if err := attachments[i].Validate(field); err != nil {
+			return nil, err
+		}
+		resource, err := m.inspectNetwork(ctx, attachments[i].Network)
+		if err != nil {
+			return nil, &NetworkConnectError{Network: attachments[i].Network, Err: err}
+		}
+		if seen[resource.ID] {
+			return nil, &ValidationError{Field: field + ".network", Message: fmt.Sprintf("%s is listed more than once", attachments[i].Network)}
+		}
+		seen[resource.ID] = true
+		if containerJSON.NetworkSettings != nil {
+			if _, ok := containerJSON.NetworkSettings.Networks[resource.Name]; ok {
+				return nil, &NetworkConnectError{Network: attachments[i].Network, Err: ErrAlreadyConnected}
+			}
+		}
+		networkIDs[i] = resource.ID
+	}
+
+	for i, networkID := range networkIDs {
+		if err := m.networkConnect(ctx, networkID, containerJSON.ID, attachments[i].endpointSettings()); err != nil {
+			return nil, &NetworkConnectError{
+				Network:     attachments[i].Network,
+				Err:         err,
+				RollbackErr: m.disconnectNetworks(containerJSON.ID, networkIDs[:i]),
+			}
+		}
+	}
+	return networkIDs, nil
+}
+
+// disconnectNetworks undoes connectNetworks. It does not use the context of the
+// request, which may be the reason of the failure.
+func (m *Manager) disconnectNetworks(containerID string, networkIDs []string) error {
+	ctx, cancel := withTimeout(context.Background(), m.Timeout)
+	defer cancel()
+
+	var result error
+	for _, networkID := range networkIDs {
+		if err := m.client.NetworkDisconnect(ctx, networkID, containerID, true); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// ConnectNetworks connects the container to all networks, or, if any of them
+// fails, to none of them and returns a *NetworkConnectError.
+func (m *Manager) ConnectNetworks(ctx context.Context, ID string, attachments []NetworkAttachment) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if len(attachments) == 0 {
+		return &ValidationError{Field: "networks", Message: "at least one network is required"}
+	}
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.connectNetworks(ctx, containerJSON, attachments)
+	return err
+}
+
+// ContainerAddress is the addressing of a container on one network.
+type ContainerAddress struct {
+	Network     string   `json:"network"`
+	NetworkID   string   `json:"network-id"`
+	IPv4Address string   `json:"ipv4-address"`
+	IPv4Gateway string   `json:"ipv4-gateway"`
+	IPv6Address string   `json:"ipv6-address"`
+	IPv6Gateway string   `json:"ipv6-gateway"`
+	MacAddress  string   `json:"mac-address"`
+	Aliases     []string `json:"aliases"`
+}
+
+// GetAddresses returns the addresses of the container on every network it is
+// attached to, sorted by network name. Unlike GetIPAddress the addresses have
+// no prefix length. Containers without any network endpoint, e.g. stopped
+// ones, result in ErrNotConnected.
+func (m *Manager) GetAddresses(ctx context.Context, ID string) ([]ContainerAddress, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []ContainerAddress{}
+	if containerJSON.NetworkSettings != nil {
+		for name, endpoint := range containerJSON.NetworkSettings.Networks {
+			if endpoint == nil || endpoint.EndpointID == "" {
+				continue
+			}
+			aliases := endpoint.Aliases
+			if aliases == nil {
+				aliases = []string{}
+			}
+			addresses = append(addresses, ContainerAddress{
+				Network:     name,
+				NetworkID:   endpoint.NetworkID,
+				IPv4Address: endpoint.IPAddress,
+				IPv4Gateway: endpoint.Gateway,
+				IPv6Address: endpoint.GlobalIPv6Address,
+				IPv6Gateway: endpoint.IPv6Gateway,
+				MacAddress:  endpoint.MacAddress,
+				Aliases:     aliases,
+			})
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, ErrNotConnected
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Network < addresses[j].Network
+	})
+	return addresses, nil
+}
@@ -0,0 +1,409 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+	"github.com/pkg/errors"
+)
+
+// sctpAPIVersion is the first docker API version publishing sctp ports.
+// Older daemons fail the create request instead of rejecting the protocol.
+const sctpAPIVersion = "1.35"
+
+// ErrNoFreePorts is returned when every port of the allocation range is taken.
+var ErrNoFreePorts = errors.New("No free host port left in the allocation range")
+
+// PortMapping publishes a container port on the host.
+type PortMapping struct {
+	ContainerPort int `json:"container-port"`
+	// HostPort 0 lets the daemon pick a free port.
+	HostPort int    `json:"host-port"`
+	HostIP   string `json:"host-ip"`
+	// Protocol is one of tcp (default), udp or sctp.
+	Protocol string `json:"protocol"`
+}
+
+func (p *PortMapping) protocol() string {
+	if p.Protocol == "" {
+		return "tcp"
+	}
+	return p.Protocol
+}
+
+func (p *PortMapping) validate(field string) error {
+	if p.ContainerPort < 1 || p.ContainerPort > 65535 {
+		return &ValidationError{Field: field + ".container-port", Message: "expected 1-65535"}
+	}
+	if p.HostPort < 0 || p.HostPort > 65535 {
+		return &ValidationError{Field: field + ".host-port", Message: "expected 0-65535"}
+	}
+	if p.HostIP != "" && net.ParseIP(p.HostIP) == nil {
+		return &ValidationError{Field: field + ".host-ip", Message: "not an IP address"}
+	}
+	switch p.protocol() {
+	case "tcp", "udp", "sctp":
+	default:
+		return &ValidationError{Field: field + ".protocol", Message: "expected tcp, udp or sctp"}
+	}
+	return nil
+}
+
+// validatePorts checks every mapping and rejects host ports bound twice.
+func validatePorts(ports []PortMapping) error {
+	bound := make(map[string]bool, len(ports))
+	for i := range ports {
+		field := fmt.Sprintf("ports[%d]", i)
+		if err := ports[i].validate(field); err != nil {
+			return err
+		}
+		if ports[i].HostPort == 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%d/%s", ports[i].HostIP, ports[i].HostPort, ports[i].protocol())
+		if bound[key] {
+			return &ValidationError{Field: field + ".host-port", Message: "bound more than once"}
+		}
+		bound[key] = true
+	}
+	return nil
+}
+
+// portBindings converts the mappings to the exposed ports and bindings of the container config.
+func portBindings(ports []PortMapping) (nat.PortSet, nat.PortMap) {
+	exposed := make(nat.PortSet, len(ports))
+	bindings := make(nat.PortMap, len(ports))
+	for _, p := range ports {
+		containerPort := nat.Port(fmt.Sprintf("%d/%s", p.ContainerPort, p.protocol()))
+		hostPort := ""
+		if p.HostPort != 0 {
+			hostPort = strconv.Itoa(p.HostPort)
+		}
+
+		exposed[containerPort] = struct{}{}
+		bindings[containerPort] = append(bindings[containerPort], nat.PortBinding{
+			HostIP:   p.HostIP,
+			HostPort: hostPort,
+		})
+	}
+	return exposed, bindings
+}
+
+// ContainerPorts returns the host ports actually bound for the container.
+// Ports are only bound while the container is running.
+func (m *Manager) ContainerPorts(ctx context.Context, ID string) ([]PortMapping, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	return boundPorts(containerJSON.NetworkSettings)
+}
+
+// boundPorts lists the host port bindings of the network settings, sorted by container port.
+func boundPorts(settings *types.NetworkSettings) ([]PortMapping, error) {
+	ports := []PortMapping{}
+	if settings == nil {
+		return ports, nil
+	}
+	for containerPort, bindings := range settings.Ports {
+		for _, binding := range bindings {
+			hostPort, err := strconv.Atoi(binding.HostPort)
+			if err != nil {
+				return nil, err
+			}
+			ports = append(ports, PortMapping{
+				ContainerPort: containerPort.Int(),
+				HostPort:      hostPort,
+				HostIP:        binding.HostIP,
+				Protocol:      containerPort.Proto(),
+			})
+		}
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].ContainerPort != ports[j].ContainerPort {
+			return ports[i].ContainerPort < ports[j].ContainerPort
+		}
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+		return ports[i].HostIP < ports[j].HostIP
+	})
+	return ports, nil
+}
+
+func hostPortKey(port int, protocol string) string {
+	return fmt.Sprintf("%d/%s", port, protocol)
+}
+
+// configuredHostPorts returns the keys of the host ports the container is
+// configured to bind, whether it is running or not. Ports left to the daemon
+// are not known before the container starts and are left out.
+func configuredHostPorts(hostConfig *container.HostConfig) []string {
+	keys := []string{}
+	if hostConfig == nil {
+		return keys
+	}
+	for containerPort, bindings := range hostConfig.PortBindings {
+		for _, binding := range bindings {
+			if binding.HostPort == "" {
+				continue
+			}
+			first, last, err := nat.ParsePortRange(binding.HostPort)
+			if err != nil {
+				continue
+			}
+			for port := first; port <= last; port++ {
+				keys = append(keys, hostPortKey(int(port), containerPort.Proto()))
+			}
+		}
+	}
+	return keys
+}
+
+// PortAllocator hands out host ports from a fixed range and remembers
+// which container each port was given to.
+type PortAllocator struct {
+	first     int
+	last      int
+	mutex     sync.Mutex
+	allocated map[string]string
+}
+
+// NewPortAllocator returns an allocator for the host ports first to last, inclusive.
+func NewPortAllocator(first int, last int) (*PortAllocator, error) {
+	if first < 1 || last > 65535 || first > last {
+		return nil, fmt.Errorf("Invalid port range %d-%d", first, last)
+	}
+	return &PortAllocator{
+		first:     first,
+		last:      last,
+		allocated: make(map[string]string),
+	}, nil
+}
+
+// allocate reserves a free port of the range that is not in inUse.
+// The reservation has no owner until assign is called.
+func (a *PortAllocator) allocate(protocol string, inUse map[string]bool) (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for port := a.first; port <= a.last; port++ {
+		key := hostPortKey(port, protocol)
+		if _, ok := a.allocated[key]; ok || inUse[key] {
+			continue
+		}
+		a.allocated[key] = ""
+		return port, nil
+	}
+	return 0, ErrNoFreePorts
+}
+
+// reserve takes the port of the key, inside the range or not, unless it is
+// handed out already, in which case its owner is returned.
+// The reservation has no owner until assign is called.
+func (a *PortAllocator) reserve(key string) (owner string, taken bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if owner, ok := a.allocated[key]; ok {
+		return owner, true
+	}
+	a.allocated[key] = ""
+	return "", false
+}
+
+func (a *PortAllocator) assign(keys []string, containerID string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, key := range keys {
+		a.allocated[key] = containerID
+	}
+}
+
+func (a *PortAllocator) release(keys []string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(a.allocated, key)
+	}
+}
+
+// Release frees the ports handed out to the container.
+func (a *PortAllocator) Release(containerID string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for key, owner := range a.allocated {
+		if owner == containerID {
+			delete(a.allocated, key)
+		}
+	}
+}
+
+// Allocated returns the handed out ports ("port/protocol") and their containers.
+func (a *PortAllocator) Allocated() map[string]string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	allocated := make(map[string]string, len(a.allocated))
+	for key, owner := range a.allocated {
+		allocated[key] = owner
+	}
+	return allocated
+}
+
+// RestorePortAllocations hands the host ports configured for managed
+// containers back to them, so the ports reserved before a restart of the
+// service are not handed out a second time. Without a port allocator nothing
+// is done.
+func (m *Manager) RestorePortAllocations(ctx context.Context) error {
+	if m.PortAllocator == nil {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	managedFilter := filters.NewArgs()
+	managedFilter.Add("label", ManagedLabel)
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: managedFilter})
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		containerJSON, err := m.client.ContainerInspect(ctx, container.ID)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		m.PortAllocator.assign(configuredHostPorts(containerJSON.HostConfig), container.ID)
+	}
+	return nil
+}
+
+// checkPortProtocols rejects sctp ports if the API version negotiated with the
+// daemon cannot publish them. Nothing is checked if the version is unknown.
+func (m *Manager) checkPortProtocols(ports []PortMapping) error {
+	if m.apiVersion == "" {
+		return nil
+	}
+	for i := range ports {
+		if ports[i].protocol() == "sctp" && versions.LessThan(m.apiVersion, sctpAPIVersion) {
+			return &ValidationError{
+				Field:   fmt.Sprintf("ports[%d].protocol", i),
+				Message: fmt.Sprintf("sctp needs docker API %s, the daemon uses %s", sctpAPIVersion, m.apiVersion),
+			}
+		}
+	}
+	return nil
+}
+
+// hostPortsInUse returns the host ports bound by running containers and
+// handed out by the port allocator, mapped to the container using them.
+// The ports of stopped containers are only known to the port allocator, if set.
+func (m *Manager) hostPortsInUse(ctx context.Context) (map[string]string, error) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := make(map[string]string)
+	if m.PortAllocator != nil {
+		inUse = m.PortAllocator.Allocated()
+	}
+	for _, container := range containers {
+		for _, port := range container.Ports {
+			if port.PublicPort != 0 {
+				inUse[hostPortKey(int(port.PublicPort), port.Type)] = container.ID
+			}
+		}
+	}
+	return inUse, nil
+}
+
+func hostPortInUseError(index int, owner string) error {
+	message := "already in use by container " + owner
+	if owner == "" {
+		message = "already in use by a container being created"
+	}
+	return &ValidationError{Field: fmt.Sprintf("ports[%d].host-port", index), Message: message}
+}
+
+// assignHostPorts checks the requested host ports against the ports in use.
+// If a port allocator is set, it reserves the requested ports and allocates the
+// ports left to 0. The returned keys identify the reserved ports.
+func (m *Manager) assignHostPorts(ctx context.Context, ports []PortMapping) ([]string, error) {
+	if len(ports) == 0 {
+		return nil, nil
+	}
+
+	inUse, err := m.hostPortsInUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for i := range ports {
+		if ports[i].HostPort == 0 {
+			continue
+		}
+		key := hostPortKey(ports[i].HostPort, ports[i].protocol())
+		owner, taken := inUse[key]
+		if !taken && m.PortAllocator != nil {
+			// Another create may have reserved the port since inUse was read.
+			owner, taken = m.PortAllocator.reserve(key)
+		}
+		if taken {
+			if m.PortAllocator != nil {
+				m.PortAllocator.release(keys)
+			}
+			return nil, hostPortInUseError(i, owner)
+		}
+		keys = append(keys, key)
+	}
+
+	if m.PortAllocator == nil {
+		return nil, nil
+	}
+
+	taken := make(map[string]bool, len(inUse))
+	for key := range inUse {
+		taken[key] = true
+	}
+
+	for i := range ports {
+		if ports[i].HostPort != 0 {
+			continue
+		}
+		port, err := m.PortAllocator.allocate(ports[i].protocol(), taken)
+		if err != nil {
+			m.PortAllocator.release(keys)
+			return nil, err
+		}
+		ports[i].HostPort = port
+		key := hostPortKey(port, ports[i].protocol())
+		taken[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
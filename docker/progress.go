@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// JSONError is the error detail reported in a progress stream.
+type JSONError struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ProgressDetail holds the byte counters of a progress message.
+type ProgressDetail struct {
+	Current int64 `json:"current,omitempty"`
+	Total   int64 `json:"total,omitempty"`
+}
+
+// JSONMessage is a single line of the progress stream the daemon returns
+// for image builds, pulls and pushes. Step and TotalSteps are filled in from
+// the "Step N/M" build output lines.
+type JSONMessage struct {
+	Stream         string           `json:"stream,omitempty"`
+	Status         string           `json:"status,omitempty"`
+	ID             string           `json:"id,omitempty"`
+	Progress       string           `json:"progress,omitempty"`
+	ProgressDetail *ProgressDetail  `json:"progressDetail,omitempty"`
+	Aux            *json.RawMessage `json:"aux,omitempty"`
+	Error          *JSONError       `json:"errorDetail,omitempty"`
+	ErrorMessage   string           `json:"error,omitempty"`
+	Step           int              `json:"step,omitempty"`
+	TotalSteps     int              `json:"total-steps,omitempty"`
+}
+
+// ProgressFunc receives the progress messages of a long running operation.
+// Returning an error aborts the operation.
+type ProgressFunc func(message JSONMessage) error
+
+var stepRegexp = regexp.MustCompile(`^Step (\d+)(?:/(\d+))? :`)
+
+func (message *JSONMessage) parseStep() {
+	match := stepRegexp.FindStringSubmatch(message.Stream)
+	if match == nil {
+		return
+	}
+	message.Step, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		message.TotalSteps, _ = strconv.Atoi(match[2])
+	}
+}
+
+// auxID returns the image ID carried by the aux field of a build message.
+func (message *JSONMessage) auxID() string {
+	if message.Aux == nil {
+		return ""
+	}
+	aux := struct {
+		ID string `json:"ID"`
+	}{}
+	if err := json.Unmarshal(*message.Aux, &aux); err != nil {
+		return ""
+	}
+	return aux.ID
+}
+
+// readJSONMessages decodes the progress stream and hands every message to progress.
+// The first error message reported by the daemon is returned as error.
+func readJSONMessages(reader io.Reader, progress ProgressFunc) error {
+	d := json.NewDecoder(reader)
+	for {
+		message := JSONMessage{}
+		if err := d.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		message.parseStep()
+
+		if progress != nil {
+			if err := progress(message); err != nil {
+				return err
+			}
+		}
+
+		if message.Error != nil {
+			return errors.New(message.Error.Message)
+		}
+		if message.ErrorMessage != "" {
+			return errors.New(message.ErrorMessage)
+		}
+	}
+}
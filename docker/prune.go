@@ -0,0 +1,273 @@
+package docker
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+const untaggedImage = "<none>:<none>"
+
+// PruneOptions selects the images removed by PruneImages. All criteria must
+// match, and at least one of DanglingOnly, Labels or OlderThan is required.
+type PruneOptions struct {
+	// DanglingOnly restricts pruning to images without tags.
+	DanglingOnly bool
+	// Labels are "key" or "key=value" entries the image labels must contain.
+	Labels []string
+	// OlderThan only selects images created before now - OlderThan.
+	OlderThan time.Duration
+	// DryRun reports the images that would be removed without removing them.
+	DryRun bool
+}
+
+// Validate rejects options that would select every unused image.
+func (o *PruneOptions) Validate() error {
+	if !o.DanglingOnly && len(o.Labels) == 0 && o.OlderThan <= 0 {
+		return &ValidationError{Field: "prune", Message: "expected at least one of dangling-only, labels or older-than"}
+	}
+	return nil
+}
+
+// RetentionPolicy limits the number of images kept per repository.
+type RetentionPolicy struct {
+	// KeepLast is the number of most recently created images kept per
+	// repository. Tags of the same image count once.
+	KeepLast int `json:"keep-last"`
+	// Repositories the policy applies to. Empty means the repositories of the
+	// images carrying ManagedLabel.
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+// Validate checks that the policy keeps at least one image per repository.
+func (p *RetentionPolicy) Validate() error {
+	if p.KeepLast < 1 {
+		return &ValidationError{Field: "keep-last", Message: "must be at least 1"}
+	}
+	return nil
+}
+
+// RemovedImage describes an image or tag removed by pruning.
+type RemovedImage struct {
+	ID      string    `json:"id"`
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+}
+
+// PruneReport lists the images removed, or to be removed in case of a dry run.
+type PruneReport struct {
+	DryRun         bool           `json:"dry-run"`
+	Images         []RemovedImage `json:"images"`
+	SpaceReclaimed int64          `json:"space-reclaimed"`
+	Errors         []string       `json:"errors"`
+}
+
+func newPruneReport(dryRun bool) *PruneReport {
+	return &PruneReport{
+		DryRun: dryRun,
+		Images: []RemovedImage{},
+		Errors: []string{},
+	}
+}
+
+func isDangling(image *types.ImageSummary) bool {
+	return len(image.RepoTags) == 0 || (len(image.RepoTags) == 1 && image.RepoTags[0] == untaggedImage)
+}
+
+func hasLabels(image *types.ImageSummary, labels []string) bool {
+	return matchLabels(image.Labels, labels)
+}
+
+// repositoryOf returns the repository part of a tag, e.g. localhost:5000/worker for localhost:5000/worker:1.0
+func repositoryOf(tag string) string {
+	index := strings.LastIndex(tag, ":")
+	if index < 0 || strings.Contains(tag[index:], "/") {
+		return tag
+	}
+	return tag[:index]
+}
+
+// imagesInUse returns the IDs of the images used by any container.
+func (m *Manager) imagesInUse(ctx context.Context) (map[string]bool, error) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		inUse[container.ImageID] = true
+	}
+	return inUse, nil
+}
+
+// PruneImages removes the images selected by options. Images used by
+// containers are never removed.
+func (m *Manager) PruneImages(ctx context.Context, options PruneOptions) (*PruneReport, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := m.client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	inUse, err := m.imagesInUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := newPruneReport(options.DryRun)
+	deadline := time.Now().Add(-options.OlderThan)
+	for i := range images {
+		image := &images[i]
+		created := time.Unix(image.Created, 0).UTC()
+		if inUse[image.ID] ||
+			(options.DanglingOnly && !isDangling(image)) ||
+			(options.OlderThan > 0 && created.After(deadline)) ||
+			!hasLabels(image, options.Labels) {
+			continue
+		}
+
+		tags := []string{}
+		if !isDangling(image) {
+			tags = image.RepoTags
+		}
+		if !options.DryRun {
+			if err := m.removeImage(ctx, image.ID, tags); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+		}
+
+		report.Images = append(report.Images, RemovedImage{ID: image.ID, Tags: tags, Created: created, Size: image.Size})
+		report.SpaceReclaimed += image.Size
+	}
+
+	return report, nil
+}
+
+// removeImage removes the image without forcing. Tagged images are removed
+// tag by tag, the daemon refuses to remove an image with several tags by ID.
+func (m *Manager) removeImage(ctx context.Context, ID string, tags []string) error {
+	if len(tags) == 0 {
+		_, err := m.client.ImageRemove(ctx, ID, types.ImageRemoveOptions{PruneChildren: true})
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := m.client.ImageRemove(ctx, tag, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repositoryImage is an image and its tags within one repository.
+type repositoryImage struct {
+	image *types.ImageSummary
+	tags  []string
+}
+
+// ApplyRetention removes the tags of all but the policy.KeepLast most recently
+// created images of each repository. An image is deleted once its last tag is
+// removed. Images used by containers keep their tags. Without
+// policy.Repositories only images carrying ManagedLabel are considered.
+func (m *Manager) ApplyRetention(ctx context.Context, policy RetentionPolicy, dryRun bool) (*PruneReport, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := m.client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	inUse, err := m.imagesInUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(policy.Repositories))
+	for _, repository := range policy.Repositories {
+		selected[repository] = true
+	}
+
+	// Group the images by repository, newest first. The tags of an image are
+	// visited one after the other, so they end up in the same entry.
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].Created > images[j].Created
+	})
+	managed := []string{ManagedLabel}
+	repositories := make(map[string][]repositoryImage)
+	for i := range images {
+		image := &images[i]
+		if isDangling(image) || (len(selected) == 0 && !hasLabels(image, managed)) {
+			continue
+		}
+		for _, tag := range image.RepoTags {
+			repository := repositoryOf(tag)
+			if len(selected) > 0 && !selected[repository] {
+				continue
+			}
+			entries := repositories[repository]
+			if last := len(entries) - 1; last >= 0 && entries[last].image.ID == image.ID {
+				entries[last].tags = append(entries[last].tags, tag)
+				continue
+			}
+			repositories[repository] = append(entries, repositoryImage{image: image, tags: []string{tag}})
+		}
+	}
+
+	report := newPruneReport(dryRun)
+	remainingTags := make(map[string]int, len(images))
+	for i := range images {
+		remainingTags[images[i].ID] = len(images[i].RepoTags)
+	}
+
+	names := make([]string, 0, len(repositories))
+	for repository := range repositories {
+		names = append(names, repository)
+	}
+	sort.Strings(names)
+	for _, repository := range names {
+		entries := repositories[repository]
+		if len(entries) <= policy.KeepLast {
+			continue
+		}
+
+		for _, entry := range entries[policy.KeepLast:] {
+			image := entry.image
+			if inUse[image.ID] {
+				continue
+			}
+
+			for _, tag := range entry.tags {
+				if !dryRun {
+					if _, err := m.client.ImageRemove(ctx, tag, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+						report.Errors = append(report.Errors, err.Error())
+						continue
+					}
+				}
+
+				removed := RemovedImage{ID: image.ID, Tags: []string{tag}, Created: time.Unix(image.Created, 0).UTC()}
+				remainingTags[image.ID]--
+				if remainingTags[image.ID] == 0 {
+					removed.Size = image.Size
+					report.SpaceReclaimed += image.Size
+				}
+				report.Images = append(report.Images, removed)
+			}
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// RegistryAuth holds the credentials used to access a registry.
+type RegistryAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"server-address,omitempty"`
+	IdentityToken string `json:"identity-token,omitempty"`
+}
+
+// encode returns the credentials in the X-Registry-Auth header format.
+// Missing credentials are sent as an empty auth config, as the daemon
+// rejects pushes without the header.
+func (a *RegistryAuth) encode() (string, error) {
+	if a == nil {
+		a = &RegistryAuth{}
+	}
+
+	payload, err := json.Marshal(types.AuthConfig{
+		Username:      a.Username,
+		Password:      a.Password,
+		ServerAddress: a.ServerAddress,
+		IdentityToken: a.IdentityToken,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(payload), nil
+}
+
+// PullResult describes the image stored by a pull.
+type PullResult struct {
+	Reference string `json:"reference"`
+	ImageID   string `json:"image-id"`
+	Digest    string `json:"digest"`
+}
+
+// PullImage pulls reference from its registry and returns the resolved image ID and digest.
+// The pull output is passed to progress, if it is not nil.
+func (m *Manager) PullImage(ctx context.Context, reference string, auth *RegistryAuth, progress ProgressFunc) (*PullResult, error) {
+	ctx, cancel := withTimeout(ctx, m.TransferTimeout)
+	defer cancel()
+
+	registryAuth, err := auth.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.client.ImagePull(ctx, reference, types.ImagePullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Close()
+
+	digest := ""
+	err = readJSONMessages(response, func(message JSONMessage) error {
+		if strings.HasPrefix(message.Status, "Digest: ") {
+			digest = strings.TrimPrefix(message.Status, "Digest: ")
+		}
+		if progress != nil {
+			return progress(message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	image, _, err := m.client.ImageInspectWithRaw(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if digest == "" && len(image.RepoDigests) > 0 {
+		digest = image.RepoDigests[0][strings.Index(image.RepoDigests[0], "@")+1:]
+	}
+
+	return &PullResult{
+		Reference: reference,
+		ImageID:   image.ID,
+		Digest:    digest,
+	}, nil
+}
+
+// TagImage adds the reference target to the image source.
+func (m *Manager) TagImage(ctx context.Context, source string, target string) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	return m.client.ImageTag(ctx, source, target)
+}
+
+// PushResult describes the manifest uploaded by a push.
+type PushResult struct {
+	Reference string `json:"reference"`
+	Tag       string `json:"tag"`
+	Digest    string `json:"digest"`
+	Size      int    `json:"size"`
+}
+
+// PushImage uploads reference to its registry. Without a tag in reference all tags
+// of the repository are pushed and the result describes the last one.
+// The push output is passed to progress, if it is not nil.
+func (m *Manager) PushImage(ctx context.Context, reference string, auth *RegistryAuth, progress ProgressFunc) (*PushResult, error) {
+	ctx, cancel := withTimeout(ctx, m.TransferTimeout)
+	defer cancel()
+
+	registryAuth, err := auth.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.client.ImagePush(ctx, reference, types.ImagePushOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Close()
+
+	result := &PushResult{
+		Reference: reference,
+	}
+	err = readJSONMessages(response, func(message JSONMessage) error {
+		// The final aux message carries the Tag, Digest and Size of the manifest.
+		if message.Aux != nil {
+			if err := json.Unmarshal(*message.Aux, result); err != nil {
+				return err
+			}
+		}
+		if progress != nil {
+			return progress(message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,161 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ContainerStats is a resource usage sample of a container.
+type ContainerStats struct {
+	Read time.Time `json:"read"`
+	// CPUPercent is relative to a single CPU, so it can exceed 100 on multi core hosts.
+	CPUPercent float64 `json:"cpu-percent"`
+	// MemoryUsage excludes the page cache, like docker stats does.
+	MemoryUsage   uint64  `json:"memory-usage"`
+	MemoryLimit   uint64  `json:"memory-limit"`
+	MemoryPercent float64 `json:"memory-percent"`
+	NetworkRx     uint64  `json:"network-rx"`
+	NetworkTx     uint64  `json:"network-tx"`
+	BlockRead     uint64  `json:"block-read"`
+	BlockWrite    uint64  `json:"block-write"`
+	Pids          uint64  `json:"pids"`
+}
+
+// computeStats derives the sample from the raw daemon statistics the same way the docker CLI does.
+func computeStats(stats *types.StatsJSON) ContainerStats {
+	result := ContainerStats{
+		Read:        stats.Read,
+		MemoryLimit: stats.MemoryStats.Limit,
+		Pids:        stats.PidsStats.Current,
+	}
+
+	// Older daemons do not report online_cpus.
+	cpus := stats.CPUStats.OnlineCPUs
+	if cpus == 0 {
+		cpus = uint32(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		result.CPUPercent = cpuDelta / systemDelta * float64(cpus) * 100
+	}
+
+	// cgroup v1 reports total_inactive_file, v2 inactive_file.
+	cache, ok := stats.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		cache = stats.MemoryStats.Stats["inactive_file"]
+	}
+	result.MemoryUsage = stats.MemoryStats.Usage
+	if cache < result.MemoryUsage {
+		result.MemoryUsage -= cache
+	}
+	if result.MemoryLimit > 0 {
+		result.MemoryPercent = float64(result.MemoryUsage) / float64(result.MemoryLimit) * 100
+	}
+
+	for _, network := range stats.Networks {
+		result.NetworkRx += network.RxBytes
+		result.NetworkTx += network.TxBytes
+	}
+
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "Read", "read":
+			result.BlockRead += entry.Value
+		case "Write", "write":
+			result.BlockWrite += entry.Value
+		}
+	}
+
+	return result
+}
+
+// StatsReader delivers the resource usage samples of a container. It has to be closed by the caller.
+type StatsReader struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+}
+
+func (m *Manager) openStats(ctx context.Context, ID string, stream bool) (*StatsReader, error) {
+	response, err := m.client.ContainerStats(ctx, ID, stream)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsReader{body: response.Body, decoder: json.NewDecoder(response.Body)}, nil
+}
+
+// next decodes the next sample. It returns io.EOF at the end of the stream.
+func (r *StatsReader) next() (*ContainerStats, error) {
+	var stats types.StatsJSON
+	if err := r.decoder.Decode(&stats); err != nil {
+		return nil, err
+	}
+
+	result := computeStats(&stats)
+	return &result, nil
+}
+
+const statsJitter = 100 * time.Millisecond
+
+// ReadStats passes a sample to handler every interval until the stream ends
+// or handler returns an error. The daemon samples once per second, so shorter
+// intervals deliver every sample.
+func (r *StatsReader) ReadStats(interval time.Duration, handler func(stats ContainerStats) error) error {
+	var last time.Time
+	for {
+		stats, err := r.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// The daemon does not sample at exact intervals.
+		if !last.IsZero() && stats.Read.Sub(last) < interval-statsJitter {
+			continue
+		}
+		last = stats.Read
+		if err := handler(*stats); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops reading the samples.
+func (r *StatsReader) Close() error {
+	return r.body.Close()
+}
+
+// StreamStats opens the resource usage stream of the container. Unknown
+// containers result in ErrContainerNotFound. The stream ends when ctx is done.
+func (m *Manager) StreamStats(ctx context.Context, ID string) (*StatsReader, error) {
+	setupCtx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if _, err := m.inspectContainer(setupCtx, ID); err != nil {
+		return nil, err
+	}
+	return m.openStats(ctx, ID, true)
+}
+
+// StatsSnapshot returns a single resource usage sample of the container.
+func (m *Manager) StatsSnapshot(ctx context.Context, ID string) (*ContainerStats, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if _, err := m.inspectContainer(ctx, ID); err != nil {
+		return nil, err
+	}
+	reader, err := m.openStats(ctx, ID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return reader.next()
+}
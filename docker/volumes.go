@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+var ErrVolumeNotFound = errors.New("Volume not found")
+var ErrVolumeExists = errors.New("Volume already exists")
+var ErrVolumeInUse = errors.New("Volume is used by a container")
+
+// VolumeSpec describes a named volume to create.
+type VolumeSpec struct {
+	Name string `json:"name"`
+	// Driver defaults to local.
+	Driver string `json:"driver"`
+	// DriverOptions are passed to the driver, e.g. type, device and o of the local driver.
+	DriverOptions map[string]string `json:"driver-options"`
+	Labels        map[string]string `json:"labels"`
+}
+
+var volumeNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// Validate checks the spec and returns a *ValidationError naming the first invalid field.
+func (s *VolumeSpec) Validate() error {
+	if s.Name == "" {
+		return &ValidationError{Field: "name", Message: "missing"}
+	}
+	if !volumeNameRegexp.MatchString(s.Name) {
+		return &ValidationError{Field: "name", Message: "only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed, at least two characters"}
+	}
+	for key := range s.DriverOptions {
+		if key == "" {
+			return &ValidationError{Field: "driver-options", Message: "empty option name"}
+		}
+	}
+	return nil
+}
+
+// VolumeDetails describes a named volume.
+type VolumeDetails struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Mountpoint string            `json:"mountpoint"`
+	Scope      string            `json:"scope"`
+	Labels     map[string]string `json:"labels"`
+	Options    map[string]string `json:"options"`
+	// Containers are the names of the containers mounting the volume. Only
+	// inspecting a volume lists them.
+	Containers []string `json:"containers,omitempty"`
+}
+
+func volumeDetails(volume *volumetypes.Volume) *VolumeDetails {
+	return &VolumeDetails{
+		Name:       volume.Name,
+		Driver:     volume.Driver,
+		Mountpoint: volume.Mountpoint,
+		Scope:      volume.Scope,
+		Labels:     volume.Labels,
+		Options:    volume.Options,
+	}
+}
+
+// inspectVolume returns ErrVolumeNotFound for unknown volumes.
+func (m *Manager) inspectVolume(ctx context.Context, name string) (volumetypes.Volume, error) {
+	volume, err := m.client.VolumeInspect(ctx, name)
+	if err != nil && errdefs.IsNotFound(err) {
+		return volume, ErrVolumeNotFound
+	}
+	return volume, err
+}
+
+// volumeUsers returns the names of all containers, running or not, mounting the volume.
+func (m *Manager) volumeUsers(ctx context.Context, name string) ([]string, error) {
+	containerFilter := filters.NewArgs()
+	containerFilter.Add("volume", name)
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: containerFilter})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, container := range containers {
+		if len(container.Names) > 0 {
+			names = append(names, strings.TrimPrefix(container.Names[0], "/"))
+		} else {
+			names = append(names, container.ID)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CreateVolume validates spec and creates the volume it describes.
+func (m *Manager) CreateVolume(ctx context.Context, spec VolumeSpec) (*VolumeDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	// The daemon returns the existing volume instead of failing.
+	if _, err := m.inspectVolume(ctx, spec.Name); err == nil {
+		return nil, ErrVolumeExists
+	} else if err != ErrVolumeNotFound {
+		return nil, err
+	}
+
+	volume, err := m.client.VolumeCreate(ctx, volumetypes.CreateOptions{
+		Name:       spec.Name,
+		Driver:     spec.Driver,
+		DriverOpts: spec.DriverOptions,
+		Labels:     managedLabels(spec.Labels),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create volume: %s", err.Error())
+	}
+	return volumeDetails(&volume), nil
+}
+
+// ListVolumes returns the volumes having all labels, "key" or "key=value"
+// entries, sorted by name.
+func (m *Manager) ListVolumes(ctx context.Context, labels []string) ([]VolumeDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	response, err := m.client.VolumeList(ctx, volumetypes.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []VolumeDetails{}
+	for _, volume := range response.Volumes {
+		if volume == nil || !matchLabels(volume.Labels, labels) {
+			continue
+		}
+		volumes = append(volumes, *volumeDetails(volume))
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+	return volumes, nil
+}
+
+// InspectVolume describes the volume and lists the containers mounting it.
+func (m *Manager) InspectVolume(ctx context.Context, name string) (*VolumeDetails, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	volume, err := m.inspectVolume(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	details := volumeDetails(&volume)
+	if details.Containers, err = m.volumeUsers(ctx, volume.Name); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// RemoveVolume removes the volume and its data. Volumes mounted by any
+// container, even a stopped one, result in ErrVolumeInUse. force is passed to
+// the driver and also removes volumes the driver cannot clean up.
+func (m *Manager) RemoveVolume(ctx context.Context, name string, force bool) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	volume, err := m.inspectVolume(ctx, name)
+	if err != nil {
+		return err
+	}
+	users, err := m.volumeUsers(ctx, volume.Name)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return ErrVolumeInUse
+	}
+
+	return m.client.VolumeRemove(ctx, volume.Name, force)
+}
+
+// checkVolumeMounts makes sure the named volumes mounted by the spec exist.
+// The daemon would silently create an empty volume for a mistyped name.
+func (m *Manager) checkVolumeMounts(ctx context.Context, mounts []MountSpec) error {
+	for i, spec := range mounts {
+		if mount.Type(spec.Type) != mount.TypeVolume {
+			continue
+		}
+		if _, err := m.inspectVolume(ctx, spec.Source); err == ErrVolumeNotFound {
+			return &ValidationError{Field: fmt.Sprintf("mounts[%d].source", i), Message: fmt.Sprintf("volume %s does not exist", spec.Source)}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// WaitCondition selects when WaitContainer returns.
+type WaitCondition string
+
+const (
+	// WaitNotRunning returns once the container is not running, immediately if it is stopped already.
+	WaitNotRunning WaitCondition = "not-running"
+	// WaitNextExit returns when the container exits the next time.
+	WaitNextExit WaitCondition = "next-exit"
+	// WaitRemoved returns once the container is removed.
+	WaitRemoved WaitCondition = "removed"
+)
+
+// WaitResult is the exit status of a container.
+type WaitResult struct {
+	ExitCode int `json:"exit-code"`
+	// Error is set if the container could not be run, e.g. its command does not exist.
+	Error string `json:"error"`
+}
+
+// WaitContainer blocks until the container reaches condition, or ctx is done.
+// The timeout of the manager does not apply. An empty condition waits for
+// WaitNotRunning. Containers that do not exist result in ErrContainerNotFound,
+// even when waiting for WaitRemoved.
+func (m *Manager) WaitContainer(ctx context.Context, ID string, condition WaitCondition) (*WaitResult, error) {
+	switch condition {
+	case "":
+		condition = WaitNotRunning
+	case WaitNotRunning, WaitNextExit, WaitRemoved:
+	default:
+		return nil, &ValidationError{Field: "condition", Message: "expected not-running, next-exit or removed"}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before looking at the state, so no exit is missed in between.
+	eventFilter := filters.NewArgs()
+	eventFilter.Add("type", events.ContainerEventType)
+	eventFilter.Add("container", ID)
+	eventFilter.Add("event", "die")
+	eventFilter.Add("event", "destroy")
+	messages, errs := m.client.Events(ctx, types.EventsOptions{Filters: eventFilter})
+
+	containerJSON, err := m.inspectContainer(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	result := &WaitResult{}
+	if containerJSON.State != nil {
+		result.ExitCode = containerJSON.State.ExitCode
+		result.Error = containerJSON.State.Error
+	}
+
+	if condition == WaitNotRunning {
+		responses, errs := m.client.ContainerWait(ctx, containerJSON.ID, container.WaitConditionNotRunning)
+		select {
+		case err := <-errs:
+			return nil, err
+		case response := <-responses:
+			result.ExitCode = int(response.StatusCode)
+			if response.Error != nil {
+				result.Error = response.Error.Message
+			} else if state, err := m.inspectContainer(ctx, containerJSON.ID); err == nil && state.State != nil {
+				result.Error = state.State.Error
+			}
+			return result, nil
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err := <-errs:
+			return nil, err
+		case message := <-messages:
+			if message.Actor.ID != containerJSON.ID {
+				continue
+			}
+
+			switch message.Action {
+			case "die":
+				result.ExitCode, _ = strconv.Atoi(message.Actor.Attributes["exitCode"])
+				result.Error = ""
+				if state, err := m.inspectContainer(ctx, containerJSON.ID); err == nil && state.State != nil {
+					result.Error = state.State.Error
+				}
+				if condition == WaitNextExit {
+					return result, nil
+				}
+			case "destroy":
+				return result, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/gorilla/websocket"
+)
+
+// getEvents sends the events of the managed containers, images, networks and
+// volumes as they happen, as newline delimited JSON, Server-Sent Events
+// (stream=sse) or WebSocket messages. The url params type, action, container
+// and label select the events, each of them may be repeated.
+func (c *Controller) getEvents(w http.ResponseWriter, r *http.Request) {
+	log.Println("Subscribing to events")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("stream")
+	if format == "" {
+		format = streamNDJSON
+	}
+	if format != streamNDJSON && format != streamSSE {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid stream format %s", format)
+		return
+	}
+
+	subscription := c.events.Subscribe(docker.EventFilter{
+		Types:      query["type"],
+		Actions:    query["action"],
+		Containers: query["container"],
+		Labels:     query["label"],
+	})
+	defer subscription.Close()
+
+	ctx := r.Context()
+	var stream eventSender
+	if websocket.IsWebSocketUpgrade(r) {
+		socket, err := newWebsocketStream(w, r)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		ctx = socket.readMessages(ctx, nil)
+		stream = socket
+	} else {
+		var err error
+		stream, err = newStreamWriter(w, format)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			stream.Finish(streamCompleted{Status: "complete"}, nil)
+			return
+		case event := <-subscription.Events():
+			if err := stream.Send("event", event); err != nil {
+				log.Println(err)
+				return
+			}
+		}
+	}
+}
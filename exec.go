@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// execContainer runs a command in a running container and returns its
+// output and exit code once it finishes.
+func (c *Controller) execContainer(w http.ResponseWriter, r *http.Request) {
+	log.Println("Executing command in container")
+	var options docker.ExecOptions
+	if err := decodePostJSON(w, r, &options); err != nil {
+		return
+	}
+
+	result, err := c.docker.Exec(r.Context(), mux.Vars(r)["id"], options)
+	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// execControl is a text message sent by the client of an attached exec session.
+type execControl struct {
+	// Type is one of stdin, resize or close-stdin.
+	Type   string `json:"type"`
+	Data   string `json:"data"`
+	Width  uint   `json:"width"`
+	Height uint   `json:"height"`
+}
+
+// execOutput carries a chunk of the command output. Chunks may split
+// multi-byte characters, so Data is sent base64 encoded.
+type execOutput struct {
+	Stream string `json:"stream"`
+	Data   []byte `json:"data"`
+}
+
+type execExit struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit-code"`
+}
+
+// attachExec starts a command in a running container and attaches it to a
+// WebSocket. Binary messages from the client are written to stdin as they are,
+// text messages are execControl commands. The output is sent as execOutput
+// messages with base64 encoded data, followed by the exit code once the command exits.
+// Url params: cmd and env (repeated), user and tty (default true).
+func (c *Controller) attachExec(w http.ResponseWriter, r *http.Request) {
+	log.Println("Attaching to command in container")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(r) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "WebSocket upgrade required")
+		return
+	}
+
+	tty, err := queryBool(r, "tty", true)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	options := docker.ExecOptions{
+		Cmd:  query["cmd"],
+		Env:  query["env"],
+		User: query.Get("user"),
+		Tty:  tty,
+	}
+
+	session, err := c.docker.StartExec(r.Context(), mux.Vars(r)["id"], options)
+	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	defer session.Close()
+
+	socket, err := newWebsocketStream(w, r)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	ctx := socket.readMessages(r.Context(), func(messageType int, data []byte) {
+		if messageType == websocket.BinaryMessage {
+			if _, err := session.Write(data); err != nil {
+				log.Println(err)
+			}
+			return
+		}
+
+		var control execControl
+		err := json.Unmarshal(data, &control)
+		if err == nil {
+			switch control.Type {
+			case "stdin":
+				_, err = session.Write([]byte(control.Data))
+			case "resize":
+				err = session.Resize(r.Context(), control.Width, control.Height)
+			case "close-stdin":
+				err = session.CloseStdin()
+			default:
+				err = fmt.Errorf("Invalid message type %s", control.Type)
+			}
+		}
+		if err != nil {
+			if errSend := socket.Send("error", streamFailure{Status: "failed", Error: err.Error()}); errSend != nil {
+				log.Println(errSend)
+			}
+		}
+	})
+	// Closing the socket detaches from the command.
+	go func() {
+		<-ctx.Done()
+		session.Close()
+	}()
+
+	err = session.ReadOutput(func(stream string, data []byte) error {
+		return socket.Send("output", execOutput{Stream: stream, Data: data})
+	})
+	exitCode := 0
+	if err == nil {
+		exitCode, err = session.ExitCode(r.Context())
+	}
+	socket.Finish(execExit{Status: "complete", ExitCode: exitCode}, err)
+}
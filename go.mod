@@ -1,17 +1,35 @@
 module github.com/artofimagination/golang-docker
 
-go 1.15
+go 1.22
 
 require (
-	github.com/Microsoft/go-winio v0.4.16 // indirect
-	github.com/docker/distribution v2.7.1+incompatible // indirect
-	github.com/docker/docker v1.13.1
+	github.com/docker/docker v24.0.7+incompatible
 	github.com/docker/go-connections v0.4.0
-	github.com/docker/go-units v0.4.0 // indirect
 	github.com/gorilla/mux v1.8.0
-	github.com/jhoonb/archivex v0.0.0-20201016144719-6a343cdae81d
-	github.com/kr/pretty v0.2.1
-	github.com/opencontainers/go-digest v1.0.0 // indirect
+	github.com/gorilla/websocket v1.4.2
+	github.com/kr/pretty v0.3.1
+	github.com/moby/patternmatcher v0.6.0
+	github.com/opencontainers/image-spec v1.1.0
 	github.com/pkg/errors v0.9.1
-	golang.org/x/net v0.0.0-20201021035429-f5854403a974 // indirect
+)
+
+require (
+	github.com/Microsoft/go-winio v0.6.2 // indirect
+	github.com/containerd/containerd v1.7.18 // indirect
+	github.com/docker/distribution v2.8.1+incompatible // indirect
+	github.com/docker/go-units v0.5.0 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/moby/sys/sequential v0.5.0 // indirect
+	github.com/moby/term v0.5.0 // indirect
+	github.com/morikuni/aec v1.0.0 // indirect
+	github.com/opencontainers/go-digest v1.0.0 // indirect
+	github.com/opencontainers/runc v1.1.12 // indirect
+	github.com/rogpeppe/go-internal v1.10.0 // indirect
+	github.com/sirupsen/logrus v1.9.3 // indirect
+	golang.org/x/net v0.23.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/time v0.3.0 // indirect
+	gotest.tools/v3 v3.5.0 // indirect
 )
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+func (c *Controller) saveImages(w http.ResponseWriter, r *http.Request) {
+	log.Println("Saving images")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	names, ok := r.URL.Query()["image-name"]
+	if !ok || len(names[0]) < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, errors.New("Url Param 'image-name' is missing"))
+		return
+	}
+
+	archive, err := c.docker.SaveImages(r.Context(), names)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", `attachment; filename="images.tar"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, archive); err != nil {
+		log.Println(errors.Wrap(errors.WithStack(err), "Failed to send image archive"))
+	}
+}
+
+// uploadedFile returns the file sent in the request body, either as the raw
+// body or as the "file" field of a multipart form.
+func uploadedFile(r *http.Request) (io.Reader, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("Missing 'file'")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+func (c *Controller) loadImages(w http.ResponseWriter, r *http.Request) {
+	log.Println("Loading images")
+	if err := checkRequestType(POST, w, r); err != nil {
+		return
+	}
+
+	archive, err := uploadedFile(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	result, err := c.docker.LoadImages(r.Context(), archive)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (c *Controller) inspectImage(w http.ResponseWriter, r *http.Request) {
+	log.Println("Inspecting image")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	details, err := c.docker.InspectImage(r.Context(), mux.Vars(r)["ref"])
+	if err == docker.ErrImageNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, details)
+}
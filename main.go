@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +18,13 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// Controller holds the dependencies of the HTTP handlers.
+type Controller struct {
+	docker    *docker.Manager
+	retention *retentionConfig
+	events    *docker.EventHub
+}
+
 func helloServer(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "Hello, I'm test server!")
 }
@@ -26,6 +34,20 @@ const (
 	GET  = "GET"
 )
 
+// requestTimeout limits how long a single request may keep the server and
+// the docker daemon busy.
+const requestTimeout = 20 * time.Second
+
+// timeoutMiddleware attaches the request deadline to the request context, so
+// the docker calls made by the handler are cancelled together with the request.
+func timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func checkRequestType(requestTypeString string, w http.ResponseWriter, r *http.Request) error {
 	if r.Method != requestTypeString {
 		w.WriteHeader(http.StatusBadRequest)
@@ -52,7 +74,36 @@ func decodePostData(w http.ResponseWriter, r *http.Request) (map[string]interfac
 	return data, nil
 }
 
-func getImage(w http.ResponseWriter, r *http.Request) {
+// decodePostJSON decodes the json body of a POST request into v.
+func decodePostJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := checkRequestType(POST, w, r); err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		err = errors.Wrap(errors.WithStack(err), "Failed to decode request json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// acceptsJSON reports whether the client asked for a json response.
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
+
+func (c *Controller) getImage(w http.ResponseWriter, r *http.Request) {
 	log.Println("Getting Image")
 	if err := checkRequestType(GET, w, r); err != nil {
 		return
@@ -65,7 +116,7 @@ func getImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	images, err := docker.ListImages()
+	images, err := c.docker.ListImages(r.Context())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
@@ -83,52 +134,91 @@ func getImage(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, names[0])
 }
 
-func createImage(w http.ResponseWriter, r *http.Request) {
+type createImageRequest struct {
+	ImageName string `json:"image-name"`
+	SourceDir string `json:"source-dir"`
+	// Stream selects the streaming response format, "ndjson" or "sse".
+	Stream string `json:"stream"`
+	docker.BuildOptions
+}
+
+type createImageResult struct {
+	Status    string `json:"status,omitempty"`
+	ImageID   string `json:"image-id"`
+	ImageName string `json:"image-name"`
+	docker.BuildOptions
+}
+
+func (c *Controller) createImage(w http.ResponseWriter, r *http.Request) {
 	log.Println("Creating Image")
-	data, err := decodePostData(w, r)
-	if err != nil {
+	request := createImageRequest{}
+	if err := decodePostJSON(w, r, &request); err != nil {
 		return
 	}
 
-	name, ok := data["image-name"].(string)
-	if !ok {
+	if request.ImageName == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprint(w, "Missing 'image-name'")
 		return
 	}
 
-	source, ok := data["source-dir"].(string)
-	if !ok {
+	if request.SourceDir == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprint(w, "Missing 'source-dir'")
 		return
 	}
 
-	if err := docker.CreateImage(source, name); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, err.Error())
+	if request.Stream != "" {
+		c.streamCreateImage(w, r, &request)
 		return
 	}
 
-	images, err := docker.ListImages()
+	// The build is limited by the BuildTimeout of the manager, not requestTimeout.
+	ID, err := c.docker.CreateImage(r.Context(), request.SourceDir, request.ImageName, request.BuildOptions, nil)
+	if _, ok := err.(*docker.ValidationError); ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
 		return
 	}
 
-	_, err = docker.GetImageIDByTag(images, name)
+	if acceptsJSON(r) {
+		writeJSON(w, http.StatusCreated, createImageResult{
+			ImageID:      ID,
+			ImageName:    request.ImageName,
+			BuildOptions: request.BuildOptions,
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, request.ImageName)
+}
+
+// streamCreateImage builds the image while sending the build output to the client.
+// The stream ends with either a "complete" event carrying the image ID or an "error" event.
+func (c *Controller) streamCreateImage(w http.ResponseWriter, r *http.Request, request *createImageRequest) {
+	stream, err := newStreamWriter(w, request.Stream)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprint(w, err.Error())
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	fmt.Fprint(w, name)
+	ID, err := c.docker.CreateImage(r.Context(), request.SourceDir, request.ImageName, request.BuildOptions, stream.progress())
+	stream.Finish(createImageResult{
+		Status:       "complete",
+		ImageID:      ID,
+		ImageName:    request.ImageName,
+		BuildOptions: request.BuildOptions,
+	}, err)
 }
 
-func deleteImage(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) deleteImage(w http.ResponseWriter, r *http.Request) {
 	log.Println("Deleting Image")
 	data, err := decodePostData(w, r)
 	if err != nil {
@@ -142,7 +232,7 @@ func deleteImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	images, err := docker.ListImages()
+	images, err := c.docker.ListImages(r.Context())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
@@ -156,13 +246,13 @@ func deleteImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := docker.DeleteImage(ID); err != nil {
+	if err := c.docker.DeleteImage(r.Context(), ID); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
 		return
 	}
 
-	images, err = docker.ListImages()
+	images, err = c.docker.ListImages(r.Context())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
@@ -180,67 +270,82 @@ func deleteImage(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Failed to delete")
 }
 
-func createContainer(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) createContainer(w http.ResponseWriter, r *http.Request) {
 	log.Println("Creating container")
-	data, err := decodePostData(w, r)
-	if err != nil {
-		return
-	}
-
-	name, ok := data["image-name"].(string)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Missing 'image-name'")
+	spec := docker.ContainerSpec{}
+	if err := decodePostJSON(w, r, &spec); err != nil {
 		return
 	}
 
-	port, ok := data["port"].(string)
-	if !ok {
+	created, err := c.docker.CreateContainer(r.Context(), spec)
+	if _, ok := err.(*docker.ValidationError); ok {
 		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Missing 'port'")
+		fmt.Fprint(w, err.Error())
 		return
 	}
-
-	address, ok := data["address"].(string)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, "Missing 'address'")
+	if err == docker.ErrNoFreePorts {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, err.Error())
 		return
 	}
-
-	ID, err := docker.CreateNewContainer(name, address, port)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
 		return
 	}
 
+	if acceptsJSON(r) {
+		writeJSON(w, http.StatusCreated, created)
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintf(w, "Container created: %s", ID)
+	fmt.Fprintf(w, "Container created: %s", created.ID)
+}
+
+type startContainerRequest struct {
+	ID       string                     `json:"id"`
+	Networks []docker.NetworkAttachment `json:"networks"`
 }
 
-func startContainer(w http.ResponseWriter, r *http.Request) {
+// startContainer starts the container given in the url params id and
+// network, which may be repeated, or in a json startContainerRequest posted
+// to set aliases, static addresses and links per network.
+func (c *Controller) startContainer(w http.ResponseWriter, r *http.Request) {
 	log.Println("Starting container")
-	if err := checkRequestType(GET, w, r); err != nil {
-		return
-	}
+	request := startContainerRequest{}
+	if r.Method == GET {
+		ids, ok := r.URL.Query()["id"]
+		if !ok || len(ids[0]) < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, errors.New("Url Param 'id' is missing"))
+			return
+		}
 
-	ids, ok := r.URL.Query()["id"]
-	if !ok || len(ids[0]) < 1 {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, errors.New("Url Param 'id' is missing"))
-		return
-	}
+		networkNames, ok := r.URL.Query()["network"]
+		if !ok || len(networkNames[0]) < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, errors.New("Url Param 'network' is missing"))
+			return
+		}
 
-	networkNames, ok := r.URL.Query()["network"]
-	if !ok || len(networkNames[0]) < 1 {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(w, errors.New("Url Param 'network' is missing"))
-		return
+		request.ID = ids[0]
+		for _, name := range networkNames {
+			request.Networks = append(request.Networks, docker.NetworkAttachment{Network: name})
+		}
+	} else {
+		if err := decodePostJSON(w, r, &request); err != nil {
+			return
+		}
+		if request.ID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "Missing 'id'")
+			return
+		}
 	}
 
-	if err := docker.StartContainer(ids[0], networkNames[0]); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err := c.docker.StartContainer(r.Context(), request.ID, request.Networks); err != nil {
+		w.WriteHeader(networkErrorStatus(err))
 		fmt.Fprint(w, err.Error())
 		return
 	}
@@ -249,7 +354,7 @@ func startContainer(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Container started")
 }
 
-func getContainerIP(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) getContainerIP(w http.ResponseWriter, r *http.Request) {
 	log.Println("Getting container IP")
 	if err := checkRequestType(GET, w, r); err != nil {
 		return
@@ -269,7 +374,7 @@ func getContainerIP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ip, err := docker.GetIPAddress(ids[0], networkNames[0])
+	ip, err := c.docker.GetIPAddress(r.Context(), ids[0], networkNames[0])
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
@@ -280,7 +385,7 @@ func getContainerIP(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, ip)
 }
 
-func stopContainer(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) stopContainer(w http.ResponseWriter, r *http.Request) {
 	log.Println("Stopping container")
 	if err := checkRequestType(GET, w, r); err != nil {
 		return
@@ -293,7 +398,7 @@ func stopContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := docker.StopContainer(ids[0]); err != nil {
+	if err := c.docker.StopContainer(r.Context(), ids[0]); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
 		return
@@ -303,7 +408,7 @@ func stopContainer(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Container stopped")
 }
 
-func getImageIDByTag(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) getImageIDByTag(w http.ResponseWriter, r *http.Request) {
 	if err := checkRequestType(GET, w, r); err != nil {
 		return
 	}
@@ -315,7 +420,7 @@ func getImageIDByTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	images, err := docker.ListImages()
+	images, err := c.docker.ListImages(r.Context())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
@@ -333,7 +438,7 @@ func getImageIDByTag(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, ID)
 }
 
-func stopContainerByImageID(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) stopContainerByImageID(w http.ResponseWriter, r *http.Request) {
 	log.Println("Stopping container by image ID")
 	if err := checkRequestType(GET, w, r); err != nil {
 		return
@@ -346,7 +451,7 @@ func stopContainerByImageID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := docker.StopContainerByImageID(ids[0]); err != nil {
+	if err := c.docker.StopContainerByImageID(r.Context(), ids[0]); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
 		return
@@ -356,7 +461,7 @@ func stopContainerByImageID(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Container stopped")
 }
 
-func deleteContainer(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) deleteContainer(w http.ResponseWriter, r *http.Request) {
 	log.Println("Deleting container")
 	data, err := decodePostData(w, r)
 	if err != nil {
@@ -370,13 +475,13 @@ func deleteContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := docker.DeleteContainer(ID); err != nil {
+	if err := c.docker.DeleteContainer(r.Context(), ID); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
 		return
 	}
 
-	containers, err := docker.ListContainers()
+	containers, err := c.docker.ListContainers(r.Context())
 	if err != nil {
 		fmt.Fprint(w, err.Error())
 		return
@@ -394,7 +499,7 @@ func deleteContainer(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Container deleted")
 }
 
-func containerExists(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) containerExists(w http.ResponseWriter, r *http.Request) {
 	log.Println("Container exists")
 	data, err := decodePostData(w, r)
 	if err != nil {
@@ -408,7 +513,7 @@ func containerExists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = docker.ContainerExists(ID)
+	err = c.docker.ContainerExists(r.Context(), ID)
 	if err == nil {
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "Container exists")
@@ -428,7 +533,7 @@ func containerExists(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func getContainer(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) getContainer(w http.ResponseWriter, r *http.Request) {
 	log.Println("Getting container")
 	if err := checkRequestType(GET, w, r); err != nil {
 		return
@@ -441,46 +546,98 @@ func getContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	containers, err := docker.ListContainers()
+	details, err := c.docker.InspectContainer(r.Context(), ids[0])
 	if err != nil {
+		w.WriteHeader(containerErrorStatus(err))
 		fmt.Fprint(w, err.Error())
 		return
 	}
 
-	for _, container := range containers {
-		if container.ID == ids[0] {
-			w.WriteHeader(http.StatusOK)
-			fmt.Fprint(w, "Container found")
-			return
-		}
+	if acceptsJSON(r) {
+		writeJSON(w, http.StatusOK, details)
+		return
 	}
-
-	w.WriteHeader(http.StatusInternalServerError)
-	fmt.Fprint(w, "Container not found")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "Container found")
 }
 
 func main() {
+	dockerManager, err := docker.NewEnvManager()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dockerManager.PortAllocator, err = loadPortAllocator()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := dockerManager.RestorePortAllocations(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	retention, err := loadRetentionConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := &Controller{
+		docker:    dockerManager,
+		retention: retention,
+		events:    dockerManager.NewEventHub(),
+	}
 
 	r := mux.NewRouter()
 	r.HandleFunc("/", helloServer)
-	r.HandleFunc("/create-image", createImage)
-	r.HandleFunc("/get-image", getImage)
-	r.HandleFunc("/delete-image", deleteImage)
-	r.HandleFunc("/get-image-id-by-tag", getImageIDByTag)
-	r.HandleFunc("/create-container", createContainer)
-	r.HandleFunc("/get-container", getContainer)
-	r.HandleFunc("/start-container", startContainer)
-	r.HandleFunc("/get-container-ip", getContainerIP)
-	r.HandleFunc("/stop-container", stopContainer)
-	r.HandleFunc("/stop-container-by-image-id", stopContainerByImageID)
-	r.HandleFunc("/delete-container", deleteContainer)
-	r.HandleFunc("/container-exists", containerExists)
+	// Routes that can stream their response manage their own deadlines.
+	r.HandleFunc("/create-image", c.createImage)
+	r.HandleFunc("/pull-image", c.pullImage)
+	r.HandleFunc("/push-image", c.pushImage)
+	r.HandleFunc("/save-images", c.saveImages)
+	r.HandleFunc("/load-images", c.loadImages)
+	r.HandleFunc("/containers/{id}/logs", c.getContainerLogs)
+	r.HandleFunc("/containers/{id}/exec/attach", c.attachExec)
+	r.HandleFunc("/containers/{id}/stats", c.getContainerStats)
+	r.HandleFunc("/events", c.getEvents)
+	r.HandleFunc("/containers/{id}/restart", c.restartContainer)
+	r.HandleFunc("/containers/{id}/wait", c.waitContainer)
+	r.HandleFunc("/containers/{id}/archive", c.containerArchive)
+
+	api := r.NewRoute().Subrouter()
+	api.Use(timeoutMiddleware)
+	api.HandleFunc("/get-image", c.getImage)
+	api.HandleFunc("/delete-image", c.deleteImage)
+	api.HandleFunc("/get-image-id-by-tag", c.getImageIDByTag)
+	api.HandleFunc("/tag-image", c.tagImage)
+	api.HandleFunc("/prune-images", c.pruneImages)
+	api.HandleFunc("/image-retention", c.imageRetention)
+	// Image references may contain slashes, e.g. localhost:5000/worker:latest
+	api.HandleFunc("/images/{ref:.+}", c.inspectImage)
+	api.HandleFunc("/create-container", c.createContainer)
+	api.HandleFunc("/get-container", c.getContainer)
+	api.HandleFunc("/start-container", c.startContainer)
+	api.HandleFunc("/get-container-ip", c.getContainerIP)
+	api.HandleFunc("/stop-container", c.stopContainer)
+	api.HandleFunc("/stop-container-by-image-id", c.stopContainerByImageID)
+	api.HandleFunc("/delete-container", c.deleteContainer)
+	api.HandleFunc("/container-exists", c.containerExists)
+	api.HandleFunc("/containers/{id}", c.inspectContainer)
+	api.HandleFunc("/containers/{id}/ports", c.getContainerPorts)
+	api.HandleFunc("/containers/{id}/exec", c.execContainer)
+	api.HandleFunc("/containers/{id}/pause", c.pauseContainer)
+	api.HandleFunc("/containers/{id}/unpause", c.unpauseContainer)
+	api.HandleFunc("/containers/{id}/kill", c.killContainer)
+	api.HandleFunc("/networks", c.networks)
+	api.HandleFunc("/networks/{name}", c.network)
+	api.HandleFunc("/networks/{name}/endpoints", c.getNetworkEndpoints)
+	api.HandleFunc("/networks/{name}/disconnect", c.disconnectNetwork)
+	api.HandleFunc("/containers/{id}/connect", c.connectNetworks)
+	api.HandleFunc("/containers/{id}/addresses", c.getContainerAddresses)
+	api.HandleFunc("/volumes", c.volumes)
+	api.HandleFunc("/volumes/{name}", c.volume)
 	// Create Server and Route Handlers
 	srv := &http.Server{
-		Handler:      r,
-		Addr:         ":8080",
-		ReadTimeout:  20 * time.Second,
-		WriteTimeout: 20 * time.Second,
+		Handler:           r,
+		Addr:              ":8080",
+		ReadHeaderTimeout: requestTimeout,
+		// No ReadTimeout and WriteTimeout: uploads and streaming responses
+		// may run for minutes. Regular routes are bounded by timeoutMiddleware instead.
 	}
 
 	// Start Server
@@ -491,6 +648,11 @@ func main() {
 		}
 	}()
 
+	go c.events.Run(context.Background())
+	if c.retention != nil {
+		go c.runRetention(context.Background())
+	}
+
 	// Graceful Shutdown
 	waitForShutdown(srv)
 }
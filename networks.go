@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/gorilla/mux"
+)
+
+func networkErrorStatus(err error) int {
+	if connectErr, ok := err.(*docker.NetworkConnectError); ok {
+		switch connectErr.Err {
+		case docker.ErrNetworkNotFound:
+			return http.StatusNotFound
+		case docker.ErrAlreadyConnected:
+			return http.StatusConflict
+		default:
+			return http.StatusInternalServerError
+		}
+	}
+	switch err {
+	case docker.ErrNetworkNotFound, docker.ErrNotConnected:
+		return http.StatusNotFound
+	case docker.ErrNetworkExists, docker.ErrNetworkInUse:
+		return http.StatusConflict
+	default:
+		return containerErrorStatus(err)
+	}
+}
+
+// networks lists the networks on GET and creates one from a json docker.NetworkSpec on POST.
+func (c *Controller) networks(w http.ResponseWriter, r *http.Request) {
+	if r.Method == GET {
+		log.Println("Listing networks")
+		networks, err := c.docker.ListNetworks(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, networks)
+		return
+	}
+
+	log.Println("Creating network")
+	spec := docker.NetworkSpec{}
+	if err := decodePostJSON(w, r, &spec); err != nil {
+		return
+	}
+
+	details, err := c.docker.CreateNetwork(r.Context(), spec)
+	if err != nil {
+		w.WriteHeader(networkErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, details)
+}
+
+// network inspects the network on GET and removes it on DELETE.
+func (c *Controller) network(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case GET:
+		log.Println("Inspecting network")
+		details, err := c.docker.InspectNetwork(r.Context(), name)
+		if err != nil {
+			w.WriteHeader(networkErrorStatus(err))
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, details)
+	case http.MethodDelete:
+		log.Println("Removing network")
+		if err := c.docker.RemoveNetwork(r.Context(), name); err != nil {
+			w.WriteHeader(networkErrorStatus(err))
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Network removed")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid request type %s", r.Method)
+	}
+}
+
+func (c *Controller) getNetworkEndpoints(w http.ResponseWriter, r *http.Request) {
+	log.Println("Getting network endpoints")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	endpoints, err := c.docker.GetNetworkEndpointResources(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		w.WriteHeader(networkErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, endpoints)
+}
+
+type disconnectRequest struct {
+	Container string `json:"container"`
+	Force     bool   `json:"force"`
+}
+
+func (c *Controller) disconnectNetwork(w http.ResponseWriter, r *http.Request) {
+	log.Println("Disconnecting container from network")
+	request := disconnectRequest{}
+	if err := decodePostJSON(w, r, &request); err != nil {
+		return
+	}
+
+	if request.Container == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "Missing 'container'")
+		return
+	}
+
+	if err := c.docker.DisconnectNetwork(r.Context(), mux.Vars(r)["name"], request.Container, request.Force); err != nil {
+		w.WriteHeader(networkErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "Container disconnected")
+}
+
+type connectRequest struct {
+	Networks []docker.NetworkAttachment `json:"networks"`
+}
+
+// connectNetworks connects the container to all networks of the json
+// connectRequest, or to none of them if any fails.
+func (c *Controller) connectNetworks(w http.ResponseWriter, r *http.Request) {
+	log.Println("Connecting container to networks")
+	request := connectRequest{}
+	if err := decodePostJSON(w, r, &request); err != nil {
+		return
+	}
+
+	if err := c.docker.ConnectNetworks(r.Context(), mux.Vars(r)["id"], request.Networks); err != nil {
+		w.WriteHeader(networkErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "Container connected")
+}
+
+// getContainerAddresses lists the addresses of the container on all its
+// networks, or only on the one given in the url param network.
+func (c *Controller) getContainerAddresses(w http.ResponseWriter, r *http.Request) {
+	log.Println("Getting container addresses")
+	if err := checkRequestType(GET, w, r); err != nil {
+		return
+	}
+
+	addresses, err := c.docker.GetAddresses(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(networkErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	if name := r.URL.Query().Get("network"); name != "" {
+		for _, address := range addresses {
+			if address.Network == name || address.NetworkID == name {
+				writeJSON(w, http.StatusOK, address)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, docker.ErrNotConnected.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, addresses)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/artofimagination/golang-docker/docker"
+)
+
+type pullImageRequest struct {
+	Image string               `json:"image"`
+	Auth  *docker.RegistryAuth `json:"auth"`
+	// Stream selects the streaming response format, "ndjson" or "sse".
+	Stream string `json:"stream"`
+}
+
+type pullImageResult struct {
+	Status string `json:"status,omitempty"`
+	*docker.PullResult
+}
+
+func (c *Controller) pullImage(w http.ResponseWriter, r *http.Request) {
+	log.Println("Pulling Image")
+	request := pullImageRequest{}
+	if err := decodePostJSON(w, r, &request); err != nil {
+		return
+	}
+
+	if request.Image == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "Missing 'image'")
+		return
+	}
+
+	if request.Stream != "" {
+		stream, err := newStreamWriter(w, request.Stream)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		result, err := c.docker.PullImage(r.Context(), request.Image, request.Auth, stream.progress())
+		stream.Finish(pullImageResult{Status: "complete", PullResult: result}, err)
+		return
+	}
+
+	// The pull is limited by the TransferTimeout of the manager, not requestTimeout.
+	result, err := c.docker.PullImage(r.Context(), request.Image, request.Auth, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pullImageResult{PullResult: result})
+}
+
+func (c *Controller) tagImage(w http.ResponseWriter, r *http.Request) {
+	log.Println("Tagging Image")
+	data, err := decodePostData(w, r)
+	if err != nil {
+		return
+	}
+
+	source, ok := data["image"].(string)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "Missing 'image'")
+		return
+	}
+
+	target, ok := data["tag"].(string)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "Missing 'tag'")
+		return
+	}
+
+	if err := c.docker.TagImage(r.Context(), source, target); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, target)
+}
+
+type pushImageRequest struct {
+	Image string               `json:"image"`
+	Auth  *docker.RegistryAuth `json:"auth"`
+	// Stream selects the streaming response format, "ndjson" or "sse".
+	Stream string `json:"stream"`
+}
+
+type pushImageResult struct {
+	Status string `json:"status,omitempty"`
+	*docker.PushResult
+}
+
+func (c *Controller) pushImage(w http.ResponseWriter, r *http.Request) {
+	log.Println("Pushing Image")
+	request := pushImageRequest{}
+	if err := decodePostJSON(w, r, &request); err != nil {
+		return
+	}
+
+	if request.Image == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "Missing 'image'")
+		return
+	}
+
+	if request.Stream != "" {
+		stream, err := newStreamWriter(w, request.Stream)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		result, err := c.docker.PushImage(r.Context(), request.Image, request.Auth, stream.progress())
+		stream.Finish(pushImageResult{Status: "complete", PushResult: result}, err)
+		return
+	}
+
+	// The push is limited by the TransferTimeout of the manager, not requestTimeout.
+	result, err := c.docker.PushImage(r.Context(), request.Image, request.Auth, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pushImageResult{PushResult: result})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/pkg/errors"
+)
+
+const defaultRetentionInterval = time.Hour
+
+// retentionConfig is the image retention policy applied on a schedule.
+type retentionConfig struct {
+	Policy   docker.RetentionPolicy `json:"policy"`
+	Interval string                 `json:"interval"`
+	interval time.Duration
+}
+
+// loadRetentionConfig reads the scheduled retention policy from the environment:
+//
+//	IMAGE_RETENTION_KEEP_LAST    number of images kept per repository, at least 1, the policy is disabled if unset
+//	IMAGE_RETENTION_REPOSITORIES comma separated repositories the policy applies to, those of managed images if unset
+//	IMAGE_RETENTION_INTERVAL     time between two runs, e.g. 30m (default 1h)
+func loadRetentionConfig() (*retentionConfig, error) {
+	keepLast, ok := os.LookupEnv("IMAGE_RETENTION_KEEP_LAST")
+	if !ok {
+		return nil, nil
+	}
+
+	config := &retentionConfig{
+		interval: defaultRetentionInterval,
+	}
+	var err error
+	config.Policy.KeepLast, err = strconv.Atoi(keepLast)
+	if err == nil {
+		err = config.Policy.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid IMAGE_RETENTION_KEEP_LAST %s", keepLast)
+	}
+
+	if repositories := os.Getenv("IMAGE_RETENTION_REPOSITORIES"); repositories != "" {
+		for _, repository := range strings.Split(repositories, ",") {
+			config.Policy.Repositories = append(config.Policy.Repositories, strings.TrimSpace(repository))
+		}
+	}
+
+	if interval := os.Getenv("IMAGE_RETENTION_INTERVAL"); interval != "" {
+		config.interval, err = time.ParseDuration(interval)
+		if err != nil || config.interval <= 0 {
+			return nil, fmt.Errorf("Invalid IMAGE_RETENTION_INTERVAL %s", interval)
+		}
+	}
+	config.Interval = config.interval.String()
+
+	return config, nil
+}
+
+// runRetention applies the configured retention policy periodically until ctx is done.
+func (c *Controller) runRetention(ctx context.Context) {
+	ticker := time.NewTicker(c.retention.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := c.docker.ApplyRetention(ctx, c.retention.Policy, false)
+			if err != nil {
+				log.Println(errors.Wrap(errors.WithStack(err), "Failed to apply image retention policy"))
+				continue
+			}
+			log.Printf("Image retention removed %d tags, reclaimed %d bytes", len(report.Images), report.SpaceReclaimed)
+			for _, errorString := range report.Errors {
+				log.Println(errorString)
+			}
+		}
+	}
+}
+
+type pruneImagesRequest struct {
+	DanglingOnly bool     `json:"dangling-only"`
+	Labels       []string `json:"labels"`
+	// OlderThan is a duration, e.g. 24h
+	OlderThan string `json:"older-than"`
+	DryRun    bool   `json:"dry-run"`
+}
+
+func (c *Controller) pruneImages(w http.ResponseWriter, r *http.Request) {
+	log.Println("Pruning images")
+	request := pruneImagesRequest{}
+	if err := decodePostJSON(w, r, &request); err != nil {
+		return
+	}
+
+	options := docker.PruneOptions{
+		DanglingOnly: request.DanglingOnly,
+		Labels:       request.Labels,
+		DryRun:       request.DryRun,
+	}
+	if request.OlderThan != "" {
+		olderThan, err := time.ParseDuration(request.OlderThan)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid 'older-than': %s", err.Error())
+			return
+		}
+		options.OlderThan = olderThan
+	}
+
+	report, err := c.docker.PruneImages(r.Context(), options)
+	if _, ok := err.(*docker.ValidationError); ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+type imageRetentionRequest struct {
+	docker.RetentionPolicy
+	DryRun bool `json:"dry-run"`
+}
+
+type imageRetentionResult struct {
+	Config *retentionConfig    `json:"config,omitempty"`
+	Report *docker.PruneReport `json:"report"`
+}
+
+// imageRetention reports what the scheduled policy would remove on GET
+// and applies the policy sent in the body on POST.
+func (c *Controller) imageRetention(w http.ResponseWriter, r *http.Request) {
+	log.Println("Image retention")
+	if r.Method == GET {
+		if c.retention == nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "No image retention policy is configured")
+			return
+		}
+
+		report, err := c.docker.ApplyRetention(r.Context(), c.retention.Policy, true)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, imageRetentionResult{Config: c.retention, Report: report})
+		return
+	}
+
+	request := imageRetentionRequest{}
+	if err := decodePostJSON(w, r, &request); err != nil {
+		return
+	}
+
+	report, err := c.docker.ApplyRetention(r.Context(), request.RetentionPolicy, request.DryRun)
+	if _, ok := err.(*docker.ValidationError); ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, imageRetentionResult{Report: report})
+}
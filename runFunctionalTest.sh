@@ -2,5 +2,5 @@ docker-compose down
 docker stop $(docker ps -aq)
 docker rm $(docker ps -aq)
 docker system prune -f
-docker-compose up --build --force-recreate -d main-server
+docker-compose up --build --force-recreate -d main-server registry
 python3 -m pytest -v test
\ No newline at end of file
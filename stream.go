@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/pkg/errors"
+)
+
+const (
+	streamNDJSON = "ndjson"
+	streamSSE    = "sse"
+)
+
+// streamWriter sends a sequence of JSON events to the client either as
+// newline delimited JSON or as Server-Sent Events, flushing after each event.
+type streamWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	sse     bool
+}
+
+func newStreamWriter(w http.ResponseWriter, format string) (*streamWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("Streaming is not supported by the connection")
+	}
+
+	switch format {
+	case streamNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	case streamSSE:
+		w.Header().Set("Content-Type", "text/event-stream")
+	default:
+		return nil, fmt.Errorf("Invalid stream format %s", format)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &streamWriter{
+		w:       w,
+		flusher: flusher,
+		sse:     format == streamSSE,
+	}, nil
+}
+
+// Send writes data as a single event. The event name is only transmitted in SSE mode.
+func (s *streamWriter) Send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if s.sse {
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	} else {
+		_, err = fmt.Fprintf(s.w, "%s\n", payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+type streamFailure struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// streamCompleted is the result of streams that have nothing else to report.
+type streamCompleted struct {
+	Status string `json:"status"`
+}
+
+// Finish ends the stream with an "error" event if err is set, otherwise with
+// a "complete" event carrying result.
+func (s *streamWriter) Finish(result interface{}, err error) {
+	if err != nil {
+		result = streamFailure{Status: "failed", Error: err.Error()}
+		if errSend := s.Send("error", result); errSend != nil {
+			log.Println(errSend)
+		}
+		return
+	}
+
+	if errSend := s.Send("complete", result); errSend != nil {
+		log.Println(errSend)
+	}
+}
+
+// progress returns a docker.ProgressFunc that forwards messages as "progress" events.
+func (s *streamWriter) progress() docker.ProgressFunc {
+	return func(message docker.JSONMessage) error {
+		return s.Send("progress", message)
+	}
+}
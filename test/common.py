@@ -60,4 +60,16 @@ def deleteImage(data, httpConnection, ID):
     if 'port' in data:
       stopContainer(data, httpConnection, ID)
     return False
-  return True
\ No newline at end of file
+  return True
+
+def deleteContainer(data, httpConnection, ID):
+  try:
+    r = httpConnection.POST("/delete-container", {"id": ID})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return False
+
+  if r.status_code != 200:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return False
+  return True
@@ -24,6 +24,10 @@ class HTTPConnector():
     url = self.URL + address
     return requests.get(url=url, params=params)
 
-  def POST(self, address, json):
+  def POST(self, address, json, stream=False, headers=None):
     url = self.URL + address
-    return requests.post(url=url, json=json)
+    return requests.post(url=url, json=json, stream=stream, headers=headers)
+
+  def UPLOAD(self, address, data):
+    url = self.URL + address
+    return requests.post(url=url, data=data, headers={"Content-Type": "application/x-tar"})
@@ -0,0 +1,876 @@
+import pytest
+import base64
+import io
+import json
+import requests
+import tarfile
+import time
+import websocket
+from functionalTest import httpConnection
+from common import *
+
+dataColumns = ("data", "expected")
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'name': 'test-container',
+      'env': ['MODE=test', 'EMPTY='],
+      'cmd': ['./main'],
+      'working-dir': '/go/src/golang-docker/workercontainer',
+      'labels': {'com.artofimagination.test': 'spec'},
+      'mounts': [{'type': 'tmpfs', 'target': '/tmp/data'}],
+      'restart-policy': {'name': 'on-failure', 'maximum-retry-count': 3}
+    },
+    "Container created"),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'restart-policy': {'name': 'sometimes'}
+    },
+    "Invalid 'restart-policy.name': expected no, always, on-failure or unless-stopped"),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'mounts': [{'type': 'bind', 'source': 'relative/path', 'target': '/data'}]
+    },
+    "Invalid 'mounts[0].source': bind mounts need an absolute host path"),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'env': ['=value']
+    },
+    "Invalid 'env[0]': expected KEY=value")
+]
+
+ids=['Success', 'Invalid restart policy', 'Invalid mount', 'Invalid env']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_CreateContainerSpec(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.text.split(":")[0] != expected and r.text != expected:
+    pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+    return
+
+  if r.status_code == 201:
+    deleteContainer(data, httpConnection, r.text.split(":")[1].strip())
+
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'ports': [
+        {'container-port': 8082, 'host-port': 9082},
+        {'container-port': 8082, 'host-port': 9083, 'host-ip': '127.0.0.1', 'protocol': 'udp'},
+        {'container-port': 8083}
+      ]
+    },
+    [
+      (8082, 'tcp', 9082),
+      (8082, 'udp', 9083),
+      (8083, 'tcp', None)
+    ]),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'ports': [{'container-port': 8082, 'protocol': 'icmp'}]
+    },
+    "Invalid 'ports[0].protocol': expected tcp, udp or sctp"),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'ports': [
+        {'container-port': 8082, 'host-port': 9082},
+        {'container-port': 8083, 'host-port': 9082}
+      ]
+    },
+    "Invalid 'ports[1].host-port': bound more than once")
+]
+
+ids=['Success', 'Invalid protocol', 'Conflict']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerPorts(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if isinstance(expected, str):
+    if r.text != expected:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+    deleteImage(data, httpConnection, data['image-name'])
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  try:
+    r = httpConnection.GET(f"/containers/{ID}/ports", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  returned = [(p['container-port'], p['protocol'], p['host-port']) for p in r.json()]
+  for containerPort, protocol, hostPort in expected:
+    found = [p for p in returned if p[0] == containerPort and p[1] == protocol and (hostPort is None or p[2] == hostPort)]
+    if len(found) == 0:
+      pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
+      break
+
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'ports': [{'container-port': 8082}, {'container-port': 8082, 'protocol': 'udp'}]
+    },
+    (20000, 20099)),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'ports': [{'container-port': 8082, 'host-port': 8080}]
+    },
+    "Invalid 'ports[0].host-port': already in use by container")
+]
+
+ids=['Allocated', 'Conflict with running container']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_HostPortAllocation(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data, headers={"Accept": "application/json"})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if isinstance(expected, str):
+    if not r.text.startswith(expected):
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+    deleteImage(data, httpConnection, data['image-name'])
+    return
+
+  created = r.json()
+  for port in created['ports']:
+    if port['host-port'] < expected[0] or port['host-port'] > expected[1]:
+      pytest.fail(f"Test failed\nReturned: {created}\nExpected: {expected}")
+      break
+
+  deleteContainer(data, httpConnection, created['id'])
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '20100',
+      'conflict': {'image-name': 'test-image:latest', 'ports': [{'container-port': 8082, 'host-port': 20100}]}
+    },
+    "Invalid 'ports[0].host-port': already in use by container"),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '20101',
+      'conflict': {'image-name': 'test-image:latest', 'port': '20101'}
+    },
+    "Invalid 'ports[0].host-port': already in use by container")
+]
+
+ids=['Ports', 'Deprecated port']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_HostPortConflictWithStoppedContainer(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  ID = createContainer(data, httpConnection)
+  if ID is None:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data['conflict'])
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 400 or not r.text.startswith(expected):
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 400 {expected}")
+
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'tail': 'all'}
+    },
+    [('stderr', 'Starting Server Worker')]),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'stderr': 'false'}
+    },
+    []),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'follow': 'true', 'until': '0s'}
+    },
+    [('stderr', 'Starting Server Worker')]),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'tail': 'last'}
+    },
+    "Invalid url param 'tail'")
+]
+
+ids=['Success', 'Stdout only', 'Follow until now', 'Invalid tail']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerLogs(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+  time.sleep(1)
+
+  try:
+    r = httpConnection.GET(f"/containers/{ID}/logs", data['params'])
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if isinstance(expected, str):
+    if r.text != expected:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+  else:
+    events = [json.loads(line) for line in r.text.splitlines()]
+    if len(events) == 0 or events[-1]['status'] != 'complete':
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+    returned = [(e['stream'], e['text']) for e in events[:-1]]
+    for stream, text in expected:
+      if len([e for e in returned if e[0] == stream and text in e[1]]) == 0:
+        pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
+        break
+    if len(expected) == 0 and len(returned) != 0:
+      pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
+
+  stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+def test_ContainerLogsNotFound(httpConnection):
+  try:
+    r = httpConnection.GET("/containers/missing-container/logs", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if r.status_code != 404 or r.text != "Container not found":
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 404 Container not found")
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'start': True,
+      'exec': {'cmd': ['sh', '-c', 'echo out; echo err >&2; exit 3']}
+    },
+    {'exit-code': 3, 'stdout': 'out\n', 'stderr': 'err\n'}),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'start': True,
+      'exec': {'cmd': []}
+    },
+    "Invalid 'cmd': missing"),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'start': False,
+      'exec': {'cmd': ['ls']}
+    },
+    "Container is not running")
+]
+
+ids=['Success', 'Missing command', 'Not running']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerExec(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if data['start'] and startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  try:
+    r = httpConnection.POST(f"/containers/{ID}/exec", data['exec'])
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if isinstance(expected, str):
+    if r.text != expected:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+  elif r.status_code != 200 or r.json() != expected:
+    pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+
+  if data['start']:
+    stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': 'cmd=sh&cmd=-c&cmd=cat%3B+exit+3&tty=false',
+      'stdin': 'héllo wörld\n'
+    },
+    {'stdout': 'héllo wörld\n', 'exit-code': 3})
+]
+
+ids=['Success']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerExecAttach(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  url = httpConnection.URL.replace("http://", "ws://") + f"/containers/{ID}/exec/attach?{data['params']}"
+  try:
+    socket = websocket.create_connection(url, timeout=30)
+  except Exception as e:
+    pytest.fail(f"Failed to open WebSocket")
+    return
+
+  # Binary messages are written to stdin as they are.
+  socket.send_binary(data['stdin'].encode())
+  socket.send(json.dumps({'type': 'close-stdin'}))
+
+  stdout = b''
+  result = None
+  while result is None:
+    message = json.loads(socket.recv())
+    if 'stream' in message:
+      stdout += base64.b64decode(message['data'])
+    else:
+      result = message
+  socket.close()
+
+  returned = {'stdout': stdout.decode(), 'exit-code': result.get('exit-code')}
+  if result.get('status') != 'complete' or returned != expected:
+    pytest.fail(f"Test failed\nReturned: {returned} {result}\nExpected: {expected}")
+
+  stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+def test_ContainerExecAttachWithoutWebSocket(httpConnection):
+  try:
+    r = httpConnection.GET("/containers/missing-container/exec/attach", {"cmd": "sh"})
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if r.status_code != 400 or r.text != "WebSocket upgrade required":
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 400 WebSocket upgrade required")
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'snapshot': 'true'}
+    },
+    1),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'interval': '1s'}
+    },
+    2),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'interval': 'soon'}
+    },
+    "Invalid url param 'interval'")
+]
+
+ids=['Snapshot', 'Stream', 'Invalid interval']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerStats(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  try:
+    r = requests.get(url=httpConnection.URL + f"/containers/{ID}/stats", params=data['params'], stream=True)
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if isinstance(expected, str):
+    if r.text != expected:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+  else:
+    samples = []
+    for line in r.iter_lines():
+      samples.append(json.loads(line))
+      if len(samples) == expected:
+        break
+    r.close()
+    for sample in samples:
+      if 'cpu-percent' not in sample or sample['memory-usage'] <= 0:
+        pytest.fail(f"Test failed\nReturned: {samples}\nExpected: {expected} samples")
+        break
+    if len(samples) != expected:
+      pytest.fail(f"Test failed\nReturned: {samples}\nExpected: {expected} samples")
+
+  stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'type': 'container', 'action': ['start', 'die']}
+    },
+    ['start', 'die']),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'stream': 'xml'}
+    },
+    "Invalid stream format xml")
+]
+
+ids=['Container lifecycle', 'Invalid format']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_Events(httpConnection, data, expected):
+  try:
+    events = requests.get(url=httpConnection.URL + "/events", params=data['params'], stream=True, timeout=30)
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if isinstance(expected, str):
+    if events.text != expected:
+      pytest.fail(f"Test failed\nReturned: {events.text}\nExpected: {expected}")
+    return
+
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+  stopContainer(data, httpConnection, ID)
+
+  returned = []
+  for line in events.iter_lines():
+    event = json.loads(line)
+    if event['id'] == ID:
+      returned.append(event['action'])
+    if returned == expected:
+      break
+  events.close()
+
+  if returned != expected:
+    pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
+
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'start': True
+    },
+    {'status': 'running', 'running': True, 'network': 'golang-docker_default'}),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'start': False
+    },
+    {'status': 'created', 'running': False, 'network': None})
+]
+
+ids=['Running', 'Created']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_InspectContainer(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if data['start'] and startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  try:
+    r = httpConnection.GET(f"/containers/{ID}", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  details = r.json()
+  if details['id'] != ID or \
+    details['config']['image'] != data['image-name'] or \
+    details['state']['status'] != expected['status'] or \
+    details['state']['running'] != expected['running'] or \
+    (expected['network'] is not None and expected['network'] not in details['network-settings']['networks']):
+    pytest.fail(f"Test failed\nReturned: {details}\nExpected: {expected}")
+
+  if data['start']:
+    stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+def test_InspectContainerNotFound(httpConnection):
+  try:
+    r = httpConnection.GET("/containers/missing-container", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if r.status_code != 404 or r.text != "Container not found":
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 404 Container not found")
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'actions': [('pause', {}), ('unpause', {}), ('restart', {'timeout': '1'}), ('kill', {'signal': 'SIGKILL'})]
+    },
+    [(200, 'paused'), (200, 'running'), (200, 'running'), (200, 'exited')]),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'actions': [('unpause', {}), ('kill', {'signal': 'not a signal'})]
+    },
+    [(409, 'Container is not paused'), (400, "Invalid 'signal': expected a signal name or number")])
+]
+
+ids=['Success', 'Invalid']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerStateChanges(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  returned = []
+  for action, params in data['actions']:
+    try:
+      r = requests.post(url=httpConnection.URL + f"/containers/{ID}/{action}", params=params)
+    except Exception as e:
+      pytest.fail(f"Failed to send POST request")
+      return
+    if r.status_code == 200:
+      returned.append((r.status_code, r.json()['status']))
+    else:
+      returned.append((r.status_code, r.text))
+
+  if returned != expected:
+    pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
+
+  stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'condition': 'not-running', 'timeout': '30s'},
+      'kill': True
+    },
+    (200, {'exit-code': 137, 'error': ''})),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'condition': 'next-exit', 'timeout': '2s'},
+      'kill': False
+    },
+    (504, "Timed out waiting for the container")),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'params': {'condition': 'gone'},
+      'kill': False
+    },
+    (400, "Invalid 'condition': expected not-running, next-exit or removed"))
+]
+
+ids=['Not running', 'Timeout', 'Invalid condition']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_WaitContainer(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  if data['kill']:
+    requests.post(url=httpConnection.URL + f"/containers/{ID}/kill")
+
+  try:
+    r = httpConnection.GET(f"/containers/{ID}/wait", data['params'])
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  returned = (r.status_code, r.json() if r.status_code == 200 else r.text)
+  if returned != expected:
+    pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
+
+  stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'path': '/tmp/hello.txt',
+      'content': b'hello worker',
+      'tar': False
+    },
+    (200, 'hello.txt', b'hello worker')),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'path': '/tmp',
+      'content': b'from archive',
+      'tar': True
+    },
+    (200, 'archived.txt', b'from archive')),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'golang-docker_default',
+      'path': '/missing/hello.txt',
+      'content': b'hello worker',
+      'tar': False
+    },
+    (404, None, None))
+]
+
+ids=['Single file', 'Archive', 'Missing directory']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_CopyFiles(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  ID = r.text.split(":")[1].strip()
+  if startContainer(data, httpConnection, ID) is False:
+    deleteContainer(data, httpConnection, ID)
+    return
+
+  body = data['content']
+  headers = {"Content-Type": "application/octet-stream"}
+  filePath = data['path']
+  if data['tar']:
+    archive = io.BytesIO()
+    with tarfile.open(fileobj=archive, mode='w') as tar:
+      info = tarfile.TarInfo(name=expected[1])
+      info.size = len(data['content'])
+      tar.addfile(info, io.BytesIO(data['content']))
+    body = archive.getvalue()
+    headers = {"Content-Type": "application/x-tar"}
+    filePath = data['path'] + '/' + expected[1]
+
+  try:
+    r = requests.put(url=httpConnection.URL + f"/containers/{ID}/archive", params={'path': data['path']}, data=body, headers=headers)
+  except Exception as e:
+    pytest.fail(f"Failed to send PUT request")
+    return
+
+  if r.status_code != expected[0]:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+  elif expected[0] == 200:
+    r = httpConnection.GET(f"/containers/{ID}/archive", {'path': filePath})
+    stat = json.loads(r.headers['X-Container-Path-Stat'])
+    with tarfile.open(fileobj=io.BytesIO(r.content)) as tar:
+      content = tar.extractfile(expected[1]).read()
+    if stat['name'] != expected[1] or stat['size'] != len(expected[2]) or content != expected[2]:
+      pytest.fail(f"Test failed\nReturned: {stat} {content}\nExpected: {expected}")
+
+  stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
@@ -16,7 +16,7 @@ createTestData = [
       'image-name': 'test-image-failure:latest',
       'source-dir': './docker'
     },
-    "close context.tar: file already closed: Error response from daemon: Cannot locate specified Dockerfile: Dockerfile")
+    "Error response from daemon: Cannot locate specified Dockerfile: Dockerfile")
 ]
 
 ids=['Success', 'Failure']
@@ -37,6 +37,143 @@ def test_CreateImage(httpConnection, data, expected):
     pytest.fail(f"Failed to cleanup test")
     return
 
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'dockerfile': 'Dockerfile',
+      'tags': ['test-image:extra'],
+      'build-args': {'UNUSED': '1'},
+      'labels': {'com.artofimagination.test': 'build-options'},
+      'no-cache': True
+    },
+    201),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'dockerfile': '../Dockerfile'
+    },
+    400),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'dockerfile': 'Dockerfile.multistage',
+      'target': 'builder'
+    },
+    201),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'dockerfile': 'Dockerfile.multistage',
+      'target': 'missing'
+    },
+    500)
+]
+
+ids=['Success', 'Dockerfile outside context', 'Target', 'Missing target']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_CreateImageOptions(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/create-image", data, headers={"Accept": "application/json"})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  if expected == 201:
+    result = r.json()
+    if result.get('tags') != data.get('tags') or result.get('labels') != data.get('labels') or \
+       result.get('build-args') != data.get('build-args') or result.get('target') != data.get('target'):
+      pytest.fail(f"Test failed\nReturned: {result}\nExpected: {data}")
+
+    for tag in data.get('tags', []):
+      deleteImage(data, httpConnection, tag)
+    if deleteImage(data, httpConnection, data['image-name']) is False:
+      pytest.fail(f"Failed to cleanup test")
+      return
+
+createTestData = [
+    ({
+      'image-name': 'test-image-context:latest',
+      'source-dir': './test/buildcontext'
+    },
+    201)
+]
+
+ids=['Ignored file left out']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_CreateImageDockerignore(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/create-image", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  # The Dockerfile fails the build if ignored.txt is part of the context.
+  if r.status_code != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'stream': 'ndjson'
+    },
+    "complete"),
+
+    ({
+      'image-name': 'test-image-failure:latest',
+      'source-dir': './docker',
+      'stream': 'ndjson'
+    },
+    "failed")
+]
+
+ids=['Success', 'Failure']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_CreateImageStream(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/create-image", data, stream=True)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  messages = [json.loads(line) for line in r.iter_lines() if line]
+  if len(messages) == 0:
+    pytest.fail(f"Test failed\nNo messages were streamed")
+    return
+
+  if messages[-1]['status'] != expected:
+    pytest.fail(f"Test failed\nReturned: {messages[-1]}\nExpected: {expected}")
+    return
+
+  if expected == "complete":
+    if not messages[-1]['image-id'].startswith("sha256:"):
+      pytest.fail(f"Test failed\nInvalid image ID: {messages[-1]['image-id']}")
+      return
+
+    if not any('step' in message for message in messages):
+      pytest.fail(f"Test failed\nNo build steps were reported")
+      return
+
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
 createTestData = [
     ({
       'image-name': 'test-image:latest',
@@ -214,7 +351,7 @@ createTestData = [
       'id': '1234',
       'network': 'golang-docker_default'
     },
-    "Failed to start container: Error response from daemon: No such container: 1234")
+    "Container not found")
 ]
 
 ids=['Success', 'No container']
@@ -0,0 +1,266 @@
+import pytest
+import json
+from functionalTest import httpConnection
+from common import *
+
+dataColumns = ("data", "expected")
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer'
+    },
+    ["test-image:latest"]),
+
+    ({
+      'image-name': 'test-image-failure:latest'
+    },
+    None)
+]
+
+ids=['Success', 'No Image']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_SaveLoadImages(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.GET("/save-images", {"image-name": data['image-name']})
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if expected is None:
+    if r.status_code != 500:
+      pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 500")
+    return
+
+  if r.status_code != 200 or r.headers['Content-Type'] != "application/x-tar":
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}")
+    return
+  archive = r.content
+
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+  try:
+    r = httpConnection.UPLOAD("/load-images", archive)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 200 or r.json()['tags'] != expected:
+    pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+    return
+
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer'
+    },
+    200),
+
+    ({
+      'image-name': 'test-image-failure:latest'
+    },
+    404)
+]
+
+ids=['Success', 'No Image']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_InspectImage(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.GET("/images/" + data['image-name'], "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if r.status_code != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  if expected == 200:
+    details = r.json()
+    if data['image-name'] not in details['repo-tags'] or "8082/tcp" not in details['config']['exposed-ports'] or len(details['history']) == 0:
+      pytest.fail(f"Test failed\nReturned: {details}")
+      return
+
+    if deleteImage(data, httpConnection, data['image-name']) is False:
+      pytest.fail(f"Failed to cleanup test")
+      return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'labels': {'com.artofimagination.prune': 'true'},
+      'prune': {'labels': ['com.artofimagination.prune=true'], 'dry-run': True}
+    },
+    1),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'labels': {'com.artofimagination.prune': 'true'},
+      'prune': {'labels': ['com.artofimagination.prune=true']}
+    },
+    1),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'labels': {'com.artofimagination.prune': 'true'},
+      'prune': {'labels': ['com.artofimagination.prune=true'], 'older-than': '24h'}
+    },
+    0)
+]
+
+ids=['Dry run', 'Prune by label', 'Too recent']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_PruneImages(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/prune-images", data['prune'])
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 200 or len(r.json()['images']) != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  try:
+    r = httpConnection.GET("/get-image", {"image-name": data['image-name']})
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  removed = 'dry-run' not in data['prune'] and expected > 0
+  if removed != (r.text == "Image not found"):
+    pytest.fail(f"Test failed\nReturned: {r.text}")
+    return
+
+  deleteImage(data, httpConnection, data['image-name'])
+
+# createRetentionImages builds a distinct image for every entry of data['builds'],
+# the first name of an entry is the image name, the others are extra tags.
+def createRetentionImages(data, httpConnection):
+  for index, names in enumerate(data['builds']):
+    build = {
+      'image-name': names[0],
+      'source-dir': './workercontainer',
+      'tags': names[1:],
+      'labels': {'retention-build': str(index)}
+    }
+    if createImage(build, httpConnection) is False:
+      return False
+  return True
+
+createTestData = [
+    ({
+      'builds': [['test-image:1'], ['test-image:2'], ['test-image:latest']],
+      'retention': {'keep-last': 1, 'repositories': ['test-image'], 'dry-run': True}
+    },
+    2),
+
+    ({
+      'builds': [['test-image:1'], ['test-image:2'], ['test-image:latest']],
+      'retention': {'keep-last': 3, 'repositories': ['test-image']}
+    },
+    0),
+
+    ({
+      'builds': [['test-image:1', 'test-image:2', 'test-image:3'], ['test-image:latest']],
+      'retention': {'keep-last': 2, 'repositories': ['test-image']}
+    },
+    0)
+]
+
+ids=['Dry run', 'Nothing to remove', 'Tags of one image count once']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ImageRetention(httpConnection, data, expected):
+  if createRetentionImages(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/image-retention", data['retention'])
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 200 or len(r.json()['report']['images']) != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  for names in data['builds']:
+    for name in names:
+      deleteImage(data, httpConnection, name)
+
+createTestData = [
+    ({
+      'builds': [['test-image:1'], ['test-image:2'], ['test-image:latest']],
+      'retention': {'keep-last': 1, 'repositories': ['test-image']}
+    },
+    2)
+]
+
+ids=['Remove tags']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ImageRetentionRemovesTags(httpConnection, data, expected):
+  if createRetentionImages(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/image-retention", data['retention'])
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 200 or len(r.json()['report']['images']) != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  removed = [tag for image in r.json()['report']['images'] for tag in image['tags']]
+  remaining = [name for names in data['builds'] for name in names if name not in removed]
+  for tag in removed:
+    r = httpConnection.GET("/get-image", {"image-name": tag})
+    if r.text != "Image not found":
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {tag} to be removed")
+
+  if len(remaining) != 1 or deleteImage(data, httpConnection, remaining[0]) is False:
+    pytest.fail(f"Failed to cleanup test\nRemaining: {remaining}")
+    return
+
+createTestData = [
+    ("/prune-images", {}, "Invalid 'prune': expected at least one of dangling-only, labels or older-than"),
+    ("/prune-images", {'dry-run': True}, "Invalid 'prune': expected at least one of dangling-only, labels or older-than"),
+    ("/image-retention", {}, "Invalid 'keep-last': must be at least 1"),
+    ("/image-retention", {'keep-last': 0, 'repositories': ['test-image']}, "Invalid 'keep-last': must be at least 1")
+]
+
+ids=['Prune without criteria', 'Prune dry run without criteria', 'Retention without keep-last', 'Retention keep-last 0']
+
+@pytest.mark.parametrize(("address", "data", "expected"), createTestData, ids=ids)
+def test_PruneRejectsUnboundedRequests(httpConnection, address, data, expected):
+  try:
+    r = httpConnection.POST(address, data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 400 or r.text != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 400 {expected}")
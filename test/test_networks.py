@@ -0,0 +1,375 @@
+import pytest
+import json
+import requests
+from functionalTest import httpConnection
+from common import *
+
+def removeNetwork(httpConnection, name):
+  try:
+    r = requests.delete(url=httpConnection.URL + f"/networks/{name}")
+  except Exception as e:
+    pytest.fail(f"Failed to send DELETE request")
+    return False
+
+  if r.status_code != 200:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return False
+  return True
+
+dataColumns = ("data", "expected")
+createTestData = [
+    ({
+      'name': 'test-network',
+      'driver': 'bridge',
+      'ipam': [{'subnet': '172.28.0.0/16', 'gateway': '172.28.0.1'}],
+      'labels': {'purpose': 'test'}
+    },
+    (201, 'bridge', '172.28.0.0/16')),
+
+    ({
+      'name': 'test-network',
+      'ipam': [{'subnet': '172.28.0.0/16', 'gateway': '10.0.0.1'}]
+    },
+    (400, "Invalid 'ipam[0].gateway': not inside the subnet", None)),
+
+    ({
+      'name': 'golang-docker_default'
+    },
+    (409, 'Network already exists', None))
+]
+
+ids=['Success', 'Invalid Gateway', 'Exists']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_CreateNetwork(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/networks", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != expected[0]:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  if expected[0] != 201:
+    if r.text != expected[1]:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected[1]}")
+    return
+
+  network = json.loads(r.text)
+  try:
+    r = httpConnection.GET("/networks", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  names = [n['name'] for n in json.loads(r.text)]
+  if network['driver'] != expected[1] or network['ipam'][0]['subnet'] != expected[2] or \
+     network['labels']['purpose'] != 'test' or data['name'] not in names:
+    pytest.fail(f"Test failed\nReturned: {network} {names}\nExpected: {expected}")
+
+  removeNetwork(httpConnection, data['name'])
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'network': 'test-network',
+      'port': '8080'
+    },
+    200)
+]
+
+ids=['Success']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ConnectDisconnectNetwork(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/networks", {'name': data['network']})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 201:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return
+
+  if createImage(data, httpConnection) is False:
+    return
+
+  ID = createContainer(data, httpConnection)
+  if ID is None or startContainer(data, httpConnection, ID) is False:
+    return
+
+  try:
+    r = httpConnection.GET(f"/networks/{data['network']}", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  containers = json.loads(r.text)['containers']
+  if r.status_code != 200 or not any(key.startswith(ID) for key in containers):
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: container {ID}")
+
+  try:
+    r = requests.delete(url=httpConnection.URL + f"/networks/{data['network']}")
+  except Exception as e:
+    pytest.fail(f"Failed to send DELETE request")
+    return
+
+  if r.status_code != 409:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 409")
+
+  try:
+    r = httpConnection.POST(f"/networks/{data['network']}/disconnect", {'container': ID})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+
+  try:
+    r = httpConnection.GET(f"/networks/{data['network']}/endpoints", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if r.status_code != 200 or json.loads(r.text) != {}:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: no endpoints")
+
+  try:
+    r = httpConnection.POST(f"/networks/{data['network']}/disconnect", {'container': ID})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 404:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 404")
+
+  stopContainer(data, httpConnection, ID)
+  deleteContainer(data, httpConnection, ID)
+  removeNetwork(httpConnection, data['network'])
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+def test_InspectNetworkNotFound(httpConnection):
+  try:
+    r = httpConnection.GET("/networks/missing-network", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if r.status_code != 404 or r.text != "Network not found":
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 404 Network not found")
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '8080',
+      'networks': [
+        {'network': 'golang-docker_default', 'aliases': ['worker']},
+        {'network': 'test-network', 'aliases': ['static-worker'], 'ipv4-address': '172.28.0.10'}
+      ]
+    },
+    (200, 'Container started', 'test-network')),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '8080',
+      'networks': [
+        {'network': 'test-network', 'ipv4-address': '172.28.0.10'},
+        {'network': 'missing-network'}
+      ]
+    },
+    (404, 'Failed to connect to network missing-network: Network not found', None)),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '8080',
+      'networks': [
+        {'network': 'test-network', 'ipv4-address': '172.28.0.300'}
+      ]
+    },
+    (400, "Invalid 'networks[0].ipv4-address': not an IPv4 address", None))
+]
+
+ids=['Success', 'Missing Network', 'Invalid Address']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_StartContainerWithNetworks(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/networks", {'name': 'test-network', 'ipam': [{'subnet': '172.28.0.0/16'}]})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 201:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return
+
+  if createImage(data, httpConnection) is False:
+    return
+
+  ID = createContainer(data, httpConnection)
+  if ID is None:
+    return
+
+  try:
+    r = httpConnection.POST("/start-container", {'id': ID, 'networks': data['networks']})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != expected[0] or r.text != expected[1]:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+
+  try:
+    r = httpConnection.GET(f"/containers/{ID}", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  networks = json.loads(r.text)['network-settings']['networks']
+  if expected[2] is None:
+    if 'test-network' in networks:
+      pytest.fail(f"Test failed\nReturned: {networks}\nExpected: test-network to be rolled back")
+  else:
+    endpoint = networks.get(expected[2], {})
+    if endpoint.get('ip-address') != '172.28.0.10' or 'static-worker' not in endpoint.get('aliases', []):
+      pytest.fail(f"Test failed\nReturned: {networks}\nExpected: static address and alias on {expected[2]}")
+    stopContainer(data, httpConnection, ID)
+
+  deleteContainer(data, httpConnection, ID)
+  removeNetwork(httpConnection, 'test-network')
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '8080',
+      'networks': [
+        {'network': 'golang-docker_default'},
+        {'network': 'test-network', 'aliases': ['static-worker'], 'ipv4-address': '172.28.0.10', 'ipv6-address': 'fd00:28::10'}
+      ]
+    },
+    (200, {'ipv4-address': '172.28.0.10', 'ipv4-gateway': '172.28.0.1', 'ipv6-address': 'fd00:28::10'})),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '8080',
+      'skip-start': 1,
+      'networks': []
+    },
+    (404, 'Container is not connected to the network'))
+]
+
+ids=['Success', 'Not attached']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerAddresses(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/networks", {
+      'name': 'test-network',
+      'enable-ipv6': True,
+      'ipam': [{'subnet': '172.28.0.0/16', 'gateway': '172.28.0.1'}, {'subnet': 'fd00:28::/64'}]
+    })
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 201:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return
+
+  if createImage(data, httpConnection) is False:
+    return
+
+  ID = createContainer(data, httpConnection)
+  if ID is None:
+    return
+
+  if 'skip-start' not in data:
+    r = httpConnection.POST("/start-container", {'id': ID, 'networks': data['networks']})
+    if r.status_code != 200:
+      pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+
+  try:
+    r = httpConnection.GET(f"/containers/{ID}/addresses", "")
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  if r.status_code != expected[0]:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+  elif expected[0] == 404:
+    if r.text != expected[1]:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected[1]}")
+  else:
+    addresses = json.loads(r.text)
+    names = [address['network'] for address in addresses]
+    r = httpConnection.GET(f"/containers/{ID}/addresses", {'network': 'test-network'})
+    address = json.loads(r.text)
+    returned = {key: address[key] for key in expected[1]}
+    if names != ['bridge', 'golang-docker_default', 'test-network'] or returned != expected[1] or \
+       'static-worker' not in address['aliases'] or '/' in addresses[0]['ipv4-address']:
+      pytest.fail(f"Test failed\nReturned: {addresses}\nExpected: {expected}")
+
+    r = httpConnection.GET(f"/containers/{ID}/addresses", {'network': 'missing-network'})
+    if r.status_code != 404:
+      pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 404")
+    stopContainer(data, httpConnection, ID)
+
+  deleteContainer(data, httpConnection, ID)
+  removeNetwork(httpConnection, 'test-network')
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({'type': 'network', 'action': ['create', 'destroy'], 'label': 'purpose=events'}, ['create', 'destroy'])
+]
+
+ids=['Network lifecycle']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_NetworkEvents(httpConnection, data, expected):
+  try:
+    events = requests.get(url=httpConnection.URL + "/events", params=data, stream=True, timeout=30)
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  try:
+    r = httpConnection.POST("/networks", {'name': 'test-network', 'labels': {'purpose': 'events'}})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 201:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return
+
+  ID = json.loads(r.text)['id']
+  removeNetwork(httpConnection, 'test-network')
+
+  returned = []
+  for line in events.iter_lines():
+    event = json.loads(line)
+    if event['id'] == ID:
+      returned.append(event['action'])
+    if returned == expected:
+      break
+  events.close()
+
+  if returned != expected:
+    pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
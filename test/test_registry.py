@@ -0,0 +1,162 @@
+import pytest
+import json
+from functionalTest import httpConnection
+from common import *
+
+dataColumns = ("data", "expected")
+createTestData = [
+    ({
+      'image': 'hello-world:latest'
+    },
+    200),
+
+    ({
+      'image': 'hello-world:latest',
+      'stream': 'ndjson'
+    },
+    "complete"),
+
+    ({
+      'image': 'localhost:5000/not-existing-image:latest'
+    },
+    500),
+
+    ({
+      'image': 'localhost:5000/not-existing-image:latest',
+      'stream': 'ndjson'
+    },
+    "failed")
+]
+
+ids=['Success', 'Success stream', 'Not found', 'Not found stream']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_PullImage(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/pull-image", data, stream='stream' in data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if 'stream' in data:
+    messages = [json.loads(line) for line in r.iter_lines() if line]
+    result = messages[-1]
+    if result['status'] != expected:
+      pytest.fail(f"Test failed\nReturned: {result}\nExpected: {expected}")
+      return
+  else:
+    if r.status_code != expected:
+      pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+      return
+    if expected != 200:
+      return
+    result = r.json()
+
+  if expected in (200, "complete"):
+    if not result['image-id'].startswith("sha256:") or not result['digest'].startswith("sha256:"):
+      pytest.fail(f"Test failed\nReturned: {result}")
+      return
+
+    if deleteImage(data, httpConnection, data['image']) is False:
+      pytest.fail(f"Failed to cleanup test")
+      return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'tag': 'test-image:tagged'
+    },
+    "test-image:tagged"),
+
+    ({
+      'image-name': 'test-image-failure:latest',
+      'tag': 'test-image:tagged'
+    },
+    "Error response from daemon: No such image: test-image-failure:latest")
+]
+
+ids=['Success', 'No Image']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_TagImage(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/tag-image", {"image": data['image-name'], "tag": data['tag']})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.text != expected:
+    pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected}")
+    return
+
+  if deleteImage(data, httpConnection, data['tag']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'tag': 'localhost:5000/test-image:latest'
+    },
+    "complete"),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'tag': 'localhost:5000/not-tagged-image:latest'
+    },
+    "failed")
+]
+
+ids=['Success', 'Not tagged']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_PushImage(httpConnection, data, expected):
+  if createImage(data, httpConnection) is False:
+    return
+
+  if expected == "complete":
+    try:
+      r = httpConnection.POST("/tag-image", {"image": data['image-name'], "tag": data['tag']})
+    except Exception as e:
+      pytest.fail(f"Failed to send POST request")
+      return
+
+  try:
+    r = httpConnection.POST("/push-image", {"image": data['tag'], "stream": "ndjson"}, stream=True)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  messages = [json.loads(line) for line in r.iter_lines() if line]
+  result = messages[-1]
+  if result['status'] != expected:
+    pytest.fail(f"Test failed\nReturned: {result}\nExpected: {expected}")
+    return
+
+  if expected == "complete":
+    if not result['digest'].startswith("sha256:"):
+      pytest.fail(f"Test failed\nReturned: {result}")
+      return
+
+    # The pushed image can be pulled back from the local registry.
+    deleteImage(data, httpConnection, data['tag'])
+    try:
+      r = httpConnection.POST("/pull-image", {"image": data['tag']})
+    except Exception as e:
+      pytest.fail(f"Failed to send POST request")
+      return
+
+    if r.status_code != 200 or r.json()['digest'] != result['digest']:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {result['digest']}")
+      return
+    deleteImage(data, httpConnection, data['tag'])
+
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
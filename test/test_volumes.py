@@ -0,0 +1,178 @@
+import pytest
+import json
+import requests
+from functionalTest import httpConnection
+from common import *
+
+def removeVolume(httpConnection, name):
+  try:
+    r = requests.delete(url=httpConnection.URL + f"/volumes/{name}")
+  except Exception as e:
+    pytest.fail(f"Failed to send DELETE request")
+    return None
+  return r
+
+dataColumns = ("data", "expected")
+createTestData = [
+    ({
+      'name': 'test-volume',
+      'driver': 'local',
+      'driver-options': {'type': 'tmpfs', 'device': 'tmpfs', 'o': 'size=10m'},
+      'labels': {'purpose': 'test'}
+    },
+    (201, 'local', 'tmpfs')),
+
+    ({
+      'name': 'a'
+    },
+    (400, "Invalid 'name': only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed, at least two characters", None))
+]
+
+ids=['Success', 'Invalid Name']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_CreateVolume(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/volumes", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != expected[0]:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+    return
+
+  if expected[0] != 201:
+    if r.text != expected[1]:
+      pytest.fail(f"Test failed\nReturned: {r.text}\nExpected: {expected[1]}")
+    return
+
+  volume = json.loads(r.text)
+  r = httpConnection.POST("/volumes", data)
+  if r.status_code != 409:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 409")
+
+  try:
+    r = httpConnection.GET("/volumes", {'label': 'purpose=test'})
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  names = [v['name'] for v in json.loads(r.text)]
+  if volume['driver'] != expected[1] or volume['options']['type'] != expected[2] or \
+     volume['labels']['purpose'] != 'test' or names != [data['name']]:
+    pytest.fail(f"Test failed\nReturned: {volume} {names}\nExpected: {expected}")
+
+  r = removeVolume(httpConnection, data['name'])
+  if r is None or r.status_code != 200:
+    pytest.fail(f"Failed to cleanup test")
+
+createTestData = [
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '8080',
+      'volume': 'test-volume',
+      'mounts': [{'type': 'volume', 'source': 'test-volume', 'target': '/data'}]
+    },
+    201),
+
+    ({
+      'image-name': 'test-image:latest',
+      'source-dir': './workercontainer',
+      'port': '8080',
+      'volume': 'test-volume',
+      'mounts': [{'type': 'volume', 'source': 'missing-volume', 'target': '/data'}]
+    },
+    400)
+]
+
+ids=['Success', 'Missing Volume']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_ContainerVolume(httpConnection, data, expected):
+  try:
+    r = httpConnection.POST("/volumes", {'name': data['volume']})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 201:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return
+
+  if createImage(data, httpConnection) is False:
+    return
+
+  try:
+    r = httpConnection.POST("/create-container", data)
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != expected:
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: {expected}")
+
+  if r.status_code == 201:
+    ID = r.text.split(":")[1].strip()
+    r = httpConnection.GET(f"/volumes/{data['volume']}", "")
+    if r.status_code != 200 or len(json.loads(r.text)['containers']) != 1:
+      pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: one container")
+
+    r = removeVolume(httpConnection, data['volume'])
+    if r is not None and r.status_code != 409:
+      pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 409")
+    deleteContainer(data, httpConnection, ID)
+
+  r = removeVolume(httpConnection, data['volume'])
+  if r is None or r.status_code != 200:
+    pytest.fail(f"Failed to cleanup test")
+
+  r = httpConnection.GET(f"/volumes/{data['volume']}", "")
+  if r.status_code != 404 or r.text != "Volume not found":
+    pytest.fail(f"Test failed\nReturned: {r.status_code} {r.text}\nExpected: 404 Volume not found")
+
+  if deleteImage(data, httpConnection, data['image-name']) is False:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+createTestData = [
+    ({'type': 'volume', 'action': ['create', 'destroy'], 'label': 'purpose=events'}, ['create', 'destroy'])
+]
+
+ids=['Volume lifecycle']
+
+@pytest.mark.parametrize(dataColumns, createTestData, ids=ids)
+def test_VolumeEvents(httpConnection, data, expected):
+  try:
+    events = requests.get(url=httpConnection.URL + "/events", params=data, stream=True, timeout=30)
+  except Exception as e:
+    pytest.fail(f"Failed to send GET request")
+    return
+
+  try:
+    r = httpConnection.POST("/volumes", {'name': 'test-volume', 'labels': {'purpose': 'events'}})
+  except Exception as e:
+    pytest.fail(f"Failed to send POST request")
+    return
+
+  if r.status_code != 201:
+    pytest.fail(f"Failed to execute request.\nDetails: {r.text}")
+    return
+
+  r = removeVolume(httpConnection, 'test-volume')
+  if r is None or r.status_code != 200:
+    pytest.fail(f"Failed to cleanup test")
+    return
+
+  returned = []
+  for line in events.iter_lines():
+    event = json.loads(line)
+    if event['id'] == 'test-volume':
+      returned.append(event['action'])
+    if returned == expected:
+      break
+  events.close()
+
+  if returned != expected:
+    pytest.fail(f"Test failed\nReturned: {returned}\nExpected: {expected}")
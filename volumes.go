@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/artofimagination/golang-docker/docker"
+	"github.com/gorilla/mux"
+)
+
+func volumeErrorStatus(err error) int {
+	switch err {
+	case docker.ErrVolumeNotFound:
+		return http.StatusNotFound
+	case docker.ErrVolumeExists, docker.ErrVolumeInUse:
+		return http.StatusConflict
+	default:
+		return containerErrorStatus(err)
+	}
+}
+
+// volumes lists the volumes having all url params label on GET and creates
+// one from a json docker.VolumeSpec on POST.
+func (c *Controller) volumes(w http.ResponseWriter, r *http.Request) {
+	if r.Method == GET {
+		log.Println("Listing volumes")
+		volumes, err := c.docker.ListVolumes(r.Context(), r.URL.Query()["label"])
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, volumes)
+		return
+	}
+
+	log.Println("Creating volume")
+	spec := docker.VolumeSpec{}
+	if err := decodePostJSON(w, r, &spec); err != nil {
+		return
+	}
+
+	details, err := c.docker.CreateVolume(r.Context(), spec)
+	if err != nil {
+		w.WriteHeader(volumeErrorStatus(err))
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, details)
+}
+
+// volume inspects the volume on GET and removes it on DELETE, passing the url
+// param force to the driver.
+func (c *Controller) volume(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case GET:
+		log.Println("Inspecting volume")
+		details, err := c.docker.InspectVolume(r.Context(), name)
+		if err != nil {
+			w.WriteHeader(volumeErrorStatus(err))
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, details)
+	case http.MethodDelete:
+		log.Println("Removing volume")
+		force, err := queryBool(r, "force", false)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		if err := c.docker.RemoveVolume(r.Context(), name, force); err != nil {
+			w.WriteHeader(volumeErrorStatus(err))
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Volume removed")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid request type %s", r.Method)
+	}
+}
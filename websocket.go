@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// eventSender is implemented by the streaming transports, streamWriter and websocketStream.
+type eventSender interface {
+	Send(event string, data interface{}) error
+	Finish(result interface{}, err error)
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// websocketStream sends JSON events as WebSocket text messages.
+// Writes are serialized, so it can be shared by several goroutines.
+type websocketStream struct {
+	conn *websocket.Conn
+	lock sync.Mutex
+}
+
+// newWebsocketStream upgrades the connection. On failure the upgrader has
+// already replied to the client.
+func newWebsocketStream(w http.ResponseWriter, r *http.Request) (*websocketStream, error) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &websocketStream{conn: conn}, nil
+}
+
+// Send writes data as a single message. The event name is not transmitted.
+func (s *websocketStream) Send(event string, data interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.conn.WriteJSON(data)
+}
+
+// Finish sends the final message like streamWriter.Finish and closes the connection.
+func (s *websocketStream) Finish(result interface{}, err error) {
+	if err != nil {
+		result = streamFailure{Status: "failed", Error: err.Error()}
+	}
+	if errSend := s.Send("", result); errSend != nil {
+		log.Println(errSend)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if errSend := s.conn.WriteMessage(websocket.CloseMessage, message); errSend != nil {
+		log.Println(errSend)
+	}
+	s.conn.Close()
+}
+
+// readMessages passes the messages received from the client to handle and
+// returns a context that is cancelled once the client closes the connection.
+// handle may be nil if the client is not expected to send anything.
+func (s *websocketStream) readMessages(ctx context.Context, handle func(messageType int, data []byte)) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		for {
+			messageType, data, err := s.conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if handle != nil {
+				handle(messageType, data)
+			}
+		}
+	}()
+	return ctx
+}